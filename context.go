@@ -6,9 +6,12 @@ package foundation
 
 import (
 	"context"
+	"crypto/x509"
 	"github.com/octofoxio/foundation/logger"
 	"github.com/rs/xid"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 )
 
 const (
@@ -17,6 +20,10 @@ const (
 	FoundationLoggerContextKey      = "logger"
 	FoundationUserIdContextKey      = "userid"
 	FoundationMethodContextKey      = "method"
+	FoundationLocaleContextKey      = "locale"
+	FoundationClaimsContextKey      = "claims"
+	FoundationClientCertContextKey  = "clientcert"
+	FoundationDebugContextKey       = "debug"
 )
 
 func AppendMethodToContext(ctx context.Context, method string, path string) context.Context {
@@ -72,6 +79,56 @@ func GetRequestIDFromContext(ctx context.Context) string {
 	}
 }
 
+// AppendLocaleToContext keep client preferred locales
+// in format of Accept-Language header (e.g. "th-TH,th;q=0.9,en;q=0.8")
+func AppendLocaleToContext(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, FoundationLocaleContextKey, locale)
+}
+
+func GetLocaleFromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(FoundationLocaleContextKey).(string); ok {
+		return locale
+	} else {
+		return ""
+	}
+}
+
+// AppendClaimsToContext keep claims of the caller, it must be
+// called by authentication middleware after the token is verified
+func AppendClaimsToContext(ctx context.Context, claims map[string]interface{}) context.Context {
+	return context.WithValue(ctx, FoundationClaimsContextKey, claims)
+}
+
+func GetClaimsFromContext(ctx context.Context) map[string]interface{} {
+	if claims, ok := ctx.Value(FoundationClaimsContextKey).(map[string]interface{}); ok {
+		return claims
+	} else {
+		return nil
+	}
+}
+
+// AppendClientCertificateToContext keep verified client certificate
+// of mTLS connection, gRPC peer is used when it is not provided
+func AppendClientCertificateToContext(ctx context.Context, cert *x509.Certificate) context.Context {
+	return context.WithValue(ctx, FoundationClientCertContextKey, cert)
+}
+
+// GetClientCertificateFromContext return verified client certificate
+// or nil when client is not authenticated by mTLS
+func GetClientCertificateFromContext(ctx context.Context) *x509.Certificate {
+	if cert, ok := ctx.Value(FoundationClientCertContextKey).(*x509.Certificate); ok && cert != nil {
+		return cert
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			if chains := tlsInfo.State.VerifiedChains; len(chains) > 0 && len(chains[0]) > 0 {
+				return chains[0][0]
+			}
+		}
+	}
+	return nil
+}
+
 func NewContext(ctx context.Context) context.Context {
 	if requestID, ok := ctx.Value(FoundationRequestIDContextKey).(string); !ok || requestID == "" {
 		requestID = xid.New().String()
@@ -27,3 +27,10 @@ func TestNewFoundationContext(t *testing.T) {
 	assert.Contains(t, b.String(), GetRequestIDFromContext(c))
 	assert.Contains(t, "ITS ME MARIO", GetUserIDFromContext(c))
 }
+
+func TestLocaleContext(t *testing.T) {
+	c := NewContext(context.Background())
+	assert.Equal(t, "", GetLocaleFromContext(c))
+	c = AppendLocaleToContext(c, "th-TH,th;q=0.9")
+	assert.Equal(t, "th-TH,th;q=0.9", GetLocaleFromContext(c))
+}
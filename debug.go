@@ -0,0 +1,241 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package foundation
+
+import (
+	"context"
+	"fmt"
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/rs/xid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"strings"
+	"sync"
+)
+
+// DebugPolicy decide whether debug information and message
+// of internal errors can be sent to the caller of this request
+type DebugPolicy func(ctx context.Context) bool
+
+var (
+	debugPolicyMux = &sync.RWMutex{}
+	debugPolicy    = DevelopmentDebugPolicy
+)
+
+// SetDebugPolicy replace policy used by gRPC server and HTTP error encoder,
+// default is DevelopmentDebugPolicy
+//
+// ```
+// foundation.SetDebugPolicy(foundation.AnyDebugPolicy(
+// foundation.DevelopmentDebugPolicy,
+// foundation.PeerDebugPolicy("user-service", "payment-service"),
+// foundation.ClaimDebugPolicy("role", "admin"),
+// ))
+// ```
+func SetDebugPolicy(policy DebugPolicy) {
+	debugPolicyMux.Lock()
+	defer debugPolicyMux.Unlock()
+	debugPolicy = policy
+}
+
+// AllowDebug report whether caller of ctx can receive debug information,
+// decision recorded by RecordDebugDecision is used when there is one
+func AllowDebug(ctx context.Context) bool {
+	if d, ok := ctx.Value(FoundationDebugContextKey).(*debugDecision); ok {
+		d.mux.Lock()
+		defer d.mux.Unlock()
+		if d.recorded {
+			return d.allowed
+		}
+	}
+	return evaluateDebugPolicy(ctx)
+}
+
+func evaluateDebugPolicy(ctx context.Context) bool {
+	debugPolicyMux.RLock()
+	var policy = debugPolicy
+	debugPolicyMux.RUnlock()
+	return policy != nil && policy(ctx)
+}
+
+// debugDecision is shared by every contexts of a request, so error
+// is sanitized by outer interceptor (or HTTP error encoder) with claims
+// which are added to context of handler by inner ones
+type debugDecision struct {
+	mux      *sync.Mutex
+	recorded bool
+	allowed  bool
+}
+
+// WithDebugDecision add holder of debug decision to ctx of request,
+// NewGRPCServer and HTTP server do this before any interceptors
+func WithDebugDecision(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(FoundationDebugContextKey).(*debugDecision); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, FoundationDebugContextKey, &debugDecision{mux: &sync.Mutex{}})
+}
+
+// RecordDebugDecision evaluate DebugPolicy with ctx of handler and keep
+// the result for AllowDebug of outer contexts, NewGRPCServer and HTTP
+// server call it when handler return error
+func RecordDebugDecision(ctx context.Context) {
+	d, ok := ctx.Value(FoundationDebugContextKey).(*debugDecision)
+	if !ok {
+		return
+	}
+	var allowed = evaluateDebugPolicy(ctx)
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.recorded = true
+	d.allowed = allowed
+}
+
+// DevelopmentDebugPolicy allow every callers when OCTOFOX_FOUNDATION_ENV
+// is development (dev or local)
+func DevelopmentDebugPolicy(ctx context.Context) bool {
+	switch strings.ToLower(EnvString(OCTOFOX_FOUNDATION_ENV, "")) {
+	case "development", "dev", "local":
+		return true
+	}
+	return false
+}
+
+// PeerDebugPolicy allow internal services which authenticated by mTLS,
+// names are matched with common name and DNS names of client certificate
+func PeerDebugPolicy(names ...string) DebugPolicy {
+	return func(ctx context.Context) bool {
+		cert := GetClientCertificateFromContext(ctx)
+		if cert == nil {
+			return false
+		}
+		for _, name := range names {
+			if cert.Subject.CommonName == name {
+				return true
+			}
+			for _, dns := range cert.DNSNames {
+				if dns == name {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// ClaimDebugPolicy allow callers which has claim key equal to value (or contain
+// value when the claim is a list), claims are from AppendClaimsToContext
+func ClaimDebugPolicy(key string, value string) DebugPolicy {
+	return func(ctx context.Context) bool {
+		switch claim := GetClaimsFromContext(ctx)[key].(type) {
+		case []string:
+			for _, v := range claim {
+				if v == value {
+					return true
+				}
+			}
+		case []interface{}:
+			for _, v := range claim {
+				if fmt.Sprint(v) == value {
+					return true
+				}
+			}
+		case nil:
+			return false
+		default:
+			return fmt.Sprint(claim) == value
+		}
+		return false
+	}
+}
+
+func AnyDebugPolicy(policies ...DebugPolicy) DebugPolicy {
+	return func(ctx context.Context) bool {
+		for _, policy := range policies {
+			if policy(ctx) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SanitizeError strip debug information and replace message of internal
+// error with generic one when caller is not allowed by the DebugPolicy
+func SanitizeError(ctx context.Context, err error) error {
+	if err == nil || AllowDebug(ctx) {
+		return err
+	}
+	return errors.Sanitize(err, GetRequestIDFromContext(ctx))
+}
+
+// SanitizeErrorServerInterceptor apply SanitizeError to returned error, it is the
+// first interceptor of NewGRPCServer so request ID is generated here when client
+// does not send it, the same ID is used by WithContextServerInterceptor.
+// Claims are added by inner interceptors, so debug decision is recorded
+// by DebugDecisionServerInterceptor which must be the last one
+func SanitizeErrorServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		ctx, requestID := incomingRequestID(ctx)
+		ctx = WithDebugDecision(ctx)
+		resp, err = handler(ctx, req)
+		if err != nil {
+			err = SanitizeError(context.WithValue(ctx, FoundationRequestIDContextKey, requestID), err)
+		}
+		return resp, err
+	}
+}
+
+func SanitizeErrorStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = WithDebugDecision(stream.Context())
+		err := handler(srv, wrapped)
+		if err != nil {
+			ctx, requestID := incomingRequestID(wrapped.Context())
+			err = SanitizeError(context.WithValue(ctx, FoundationRequestIDContextKey, requestID), err)
+		}
+		return err
+	}
+}
+
+// DebugDecisionServerInterceptor record debug decision with context
+// which handler receive, it is the last interceptor of NewGRPCServer
+func DebugDecisionServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			RecordDebugDecision(ctx)
+		}
+		return resp, err
+	}
+}
+
+func DebugDecisionStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, stream)
+		if err != nil {
+			RecordDebugDecision(stream.Context())
+		}
+		return err
+	}
+}
+
+// incomingRequestID return request ID from incoming metadata,
+// new ID is generated and added to the metadata if there is none
+func incomingRequestID(ctx context.Context) (context.Context, string) {
+	if requestID := GetRequestIDFromContext(ctx); requestID != "" {
+		return ctx, requestID
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if requestIDs := md.Get(GRPC_METADATA_REQUEST_ID_KEY); len(requestIDs) > 0 && requestIDs[0] != "" {
+		return ctx, requestIDs[0]
+	}
+	var requestID = xid.New().String()
+	md = md.Copy()
+	md.Set(GRPC_METADATA_REQUEST_ID_KEY, requestID)
+	return metadata.NewIncomingContext(ctx, md), requestID
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package foundation
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"os"
+	"testing"
+)
+
+func TestDebugPolicy(t *testing.T) {
+	var ctx = context.Background()
+
+	t.Run("development", func(t *testing.T) {
+		defer os.Unsetenv(OCTOFOX_FOUNDATION_ENV)
+		_ = os.Setenv(OCTOFOX_FOUNDATION_ENV, "production")
+		assert.False(t, DevelopmentDebugPolicy(ctx))
+		_ = os.Setenv(OCTOFOX_FOUNDATION_ENV, "Development")
+		assert.True(t, DevelopmentDebugPolicy(ctx))
+	})
+
+	t.Run("peer", func(t *testing.T) {
+		var policy = PeerDebugPolicy("user-service")
+		assert.False(t, policy(ctx))
+
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: "user-service"}}
+		assert.True(t, policy(AppendClientCertificateToContext(ctx, cert)))
+
+		cert = &x509.Certificate{DNSNames: []string{"user-service"}}
+		grpcCtx := peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{cert}},
+		}}})
+		assert.True(t, policy(grpcCtx))
+
+		unverified := peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+		}}})
+		assert.False(t, policy(unverified), "only verified certificate is trusted")
+	})
+
+	t.Run("claim", func(t *testing.T) {
+		var policy = ClaimDebugPolicy("role", "admin")
+		assert.False(t, policy(ctx))
+		assert.False(t, policy(AppendClaimsToContext(ctx, map[string]interface{}{"role": "user"})))
+		assert.True(t, policy(AppendClaimsToContext(ctx, map[string]interface{}{"role": "admin"})))
+		assert.True(t, policy(AppendClaimsToContext(ctx, map[string]interface{}{"role": []interface{}{"user", "admin"}})))
+	})
+
+	t.Run("any", func(t *testing.T) {
+		assert.False(t, AnyDebugPolicy()(ctx))
+		assert.True(t, AnyDebugPolicy(ClaimDebugPolicy("role", "admin"), func(ctx context.Context) bool { return true })(ctx))
+	})
+}
+
+func TestSanitizeErrorServerInterceptor(t *testing.T) {
+	defer SetDebugPolicy(DevelopmentDebugPolicy)
+	SetDebugPolicy(ClaimDebugPolicy("role", "admin"))
+
+	var (
+		interceptor = SanitizeErrorServerInterceptor()
+		info        = &grpc.UnaryServerInfo{FullMethod: "/test.Test/Ping"}
+		requestID   string
+	)
+	var handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		requestID = md.Get(GRPC_METADATA_REQUEST_ID_KEY)[0]
+		return nil, errors.New(errors.ErrorTypeInternal, "pq: password authentication failed")
+	}
+
+	_, err := interceptor(context.Background(), nil, info, handler)
+	assert.NotEmpty(t, requestID, "request ID must be generated for handler")
+	assert.Equal(t, "internal server error (request ID: "+requestID+")", status.Convert(err).Message())
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(GRPC_METADATA_REQUEST_ID_KEY, "request-1"))
+	_, err = interceptor(ctx, nil, info, handler)
+	assert.Equal(t, "request-1", requestID)
+	assert.Contains(t, status.Convert(err).Message(), "request-1")
+
+	ctx = AppendClaimsToContext(context.Background(), map[string]interface{}{"role": "admin"})
+	_, err = interceptor(ctx, nil, info, handler)
+	assert.Equal(t, "pq: password authentication failed", status.Convert(err).Message())
+}
+
+func TestSanitizeErrorServerInterceptor_ClaimsFromInnerInterceptor(t *testing.T) {
+	defer SetDebugPolicy(DevelopmentDebugPolicy)
+	SetDebugPolicy(ClaimDebugPolicy("role", "admin"))
+
+	var auth = func(role string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			return handler(AppendClaimsToContext(ctx, map[string]interface{}{"role": role}), req)
+		}
+	}
+	var handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.New(errors.ErrorTypeInternal, "pq: password authentication failed")
+	}
+	var call = func(role string) error {
+		chain := grpc_middleware.ChainUnaryServer(SanitizeErrorServerInterceptor(), auth(role), DebugDecisionServerInterceptor())
+		_, err := chain(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Test/Ping"}, handler)
+		return err
+	}
+
+	assert.Equal(t, "pq: password authentication failed", status.Convert(call("admin")).Message())
+	assert.Contains(t, status.Convert(call("user")).Message(), "internal server error")
+}
+
+func TestNewGRPCServer_SanitizeKeepCustomDetails(t *testing.T) {
+	var lis = bufconn.Listen(1 << 20)
+	var server = NewGRPCServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Test",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Ping",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				var handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+					st, _ := status.New(codes.FailedPrecondition, "email is not verified").WithDetails(&errdetails.PreconditionFailure{
+						Violations: []*errdetails.PreconditionFailure_Violation{{Type: "EMAIL", Subject: "user:1", Description: "not verified"}},
+					})
+					return nil, st.Err()
+				}
+				return interceptor(ctx, &PingInput{}, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/test.Test/Ping"}, handler)
+			},
+		}},
+	}, struct{}{})
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	defer server.Stop()
+
+	conn, err := grpc.Dial("bufconn", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	defer SetDebugPolicy(DevelopmentDebugPolicy)
+	SetDebugPolicy(ClaimDebugPolicy("role", "admin"))
+	err = conn.Invoke(context.Background(), "/test.Test/Ping", &PingInput{}, &PingOutput{})
+	st := status.Convert(err)
+	assert.Equal(t, codes.FailedPrecondition, st.Code())
+	assert.Equal(t, "email is not verified", st.Message())
+	var precondition *errdetails.PreconditionFailure
+	for _, d := range st.Details() {
+		_, isDebug := d.(*errdetails.DebugInfo)
+		assert.False(t, isDebug, "debug info must be removed")
+		if p, ok := d.(*errdetails.PreconditionFailure); ok {
+			precondition = p
+		}
+	}
+	if assert.NotNil(t, precondition) {
+		assert.Equal(t, "not verified", precondition.Violations[0].Description)
+	}
+}
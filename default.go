@@ -20,8 +20,13 @@ const (
 	OCTOFOX_FOUNDATION_GRPC_CERT = "OCTOFOX_FOUNDATION_GRPC_CERT"
 	OCTOFOX_FOUNDATION_GRPC_KEY  = "OCTOFOX_FOUNDATION_GRPC_KEY"
 
-	GRPC_METADATA_AUTHORIZATION_KEY = "Authorization"
-	GRPC_METADATA_REQUEST_ID_KEY    = "RequestID"
+	// Environment of service (e.g. development, staging, production)
+	// debug information of error is sent to client in development only
+	OCTOFOX_FOUNDATION_ENV = "OCTOFOX_FOUNDATION_ENV"
+
+	GRPC_METADATA_AUTHORIZATION_KEY   = "Authorization"
+	GRPC_METADATA_REQUEST_ID_KEY      = "RequestID"
+	GRPC_METADATA_ACCEPT_LANGUAGE_KEY = "Accept-Language"
 )
 
 func String(v string) *string {
@@ -0,0 +1,173 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Params is values of message template placeholders
+type Params map[string]interface{}
+
+// Definition is a domain error which is defined once in catalog
+// and every error created from it share the same reason
+//
+// ```
+// var ErrUserNotFound = errors.Define("USER_NOT_FOUND", errors.ErrorTypeNotfound, "user {id} not found", "Returned when the user does not exist")
+// return ErrUserNotFound.New(errors.Params{"id": id})
+// ```
+type Definition struct {
+	Reason  string
+	Type    ErrorType
+	Message string
+	Doc     string
+}
+
+var (
+	catalogMux  = &sync.RWMutex{}
+	definitions = map[string]*Definition{}
+)
+
+var templatePlaceholder = regexp.MustCompile(`{([A-Za-z0-9_]+)}`)
+
+// Define register error to the catalog, reason must be unique
+// message may contain placeholders in format of {name}
+func Define(reason string, t ErrorType, message, doc string) *Definition {
+	catalogMux.Lock()
+	defer catalogMux.Unlock()
+	if _, exists := definitions[reason]; exists {
+		panic(fmt.Sprintf("error reason %s is already defined", reason))
+	}
+	var d = &Definition{
+		Reason:  reason,
+		Type:    t,
+		Message: message,
+		Doc:     doc,
+	}
+	definitions[reason] = d
+	return d
+}
+
+// Lookup find definition by reason
+func Lookup(reason string) (*Definition, bool) {
+	catalogMux.RLock()
+	defer catalogMux.RUnlock()
+	d, ok := definitions[reason]
+	return d, ok
+}
+
+// Catalog return every definitions ordered by reason
+func Catalog() []*Definition {
+	catalogMux.RLock()
+	var result = make([]*Definition, 0, len(definitions))
+	for _, d := range definitions {
+		result = append(result, d)
+	}
+	catalogMux.RUnlock()
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Reason < result[j].Reason
+	})
+	return result
+}
+
+// New create error from definition and render message with params
+func (d *Definition) New(params Params) *Error {
+	return &Error{
+		code:    d.Type,
+		reason:  d.Reason,
+		message: renderTemplate(d.Message, params),
+		params:  params,
+		stack:   callers(1),
+	}
+}
+
+// Wrap create error from definition with err as its cause
+func (d *Definition) Wrap(err error, params Params) *Error {
+	var e = d.New(params)
+	e.cause = err
+	e.stack = callers(1)
+	return e
+}
+
+// Error allow definition to be used as errors.Is target
+func (d *Definition) Error() string {
+	return d.Reason
+}
+
+// Params return placeholder names in message template
+func (d *Definition) Params() []string {
+	var result []string
+	for _, match := range templatePlaceholder.FindAllStringSubmatch(d.Message, -1) {
+		result = append(result, match[1])
+	}
+	return result
+}
+
+func renderTemplate(template string, params Params) string {
+	return templatePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		if v, ok := params[name]; ok {
+			return fmt.Sprint(v)
+		}
+		return placeholder
+	})
+}
+
+type catalogEntry struct {
+	Reason     string   `json:"reason"`
+	Type       int      `json:"type"`
+	Code       string   `json:"code"`
+	HTTPStatus int      `json:"httpStatus"`
+	Message    string   `json:"message"`
+	Params     []string `json:"params,omitempty"`
+	Doc        string   `json:"doc"`
+}
+
+func catalogEntries() []catalogEntry {
+	var result []catalogEntry
+	for _, d := range Catalog() {
+		result = append(result, catalogEntry{
+			Reason:     d.Reason,
+			Type:       int(d.Type),
+			Code:       CodeFromType(d.Type).String(),
+			HTTPStatus: d.Type.HTTPStatus(),
+			Message:    d.Message,
+			Params:     d.Params(),
+			Doc:        d.Doc,
+		})
+	}
+	return result
+}
+
+// WriteCatalogJSON dump the catalog as JSON array
+func WriteCatalogJSON(w io.Writer) error {
+	var encoder = json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	var entries = catalogEntries()
+	if entries == nil {
+		entries = []catalogEntry{}
+	}
+	return encoder.Encode(entries)
+}
+
+// WriteCatalogMarkdown dump the catalog as Markdown table
+func WriteCatalogMarkdown(w io.Writer) error {
+	escape := strings.NewReplacer("|", "\\|", "\n", " ")
+	var b strings.Builder
+	b.WriteString("| Reason | gRPC code | HTTP status | Message | Description |\n")
+	b.WriteString("|---|---|---|---|---|\n")
+	for _, e := range catalogEntries() {
+		b.WriteString(fmt.Sprintf("| `%s` | %s | %d | %s | %s |\n",
+			e.Reason, e.Code, e.HTTPStatus, escape.Replace(e.Message), escape.Replace(e.Doc)))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
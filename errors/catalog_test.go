@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var errTestOrderNotFound = Define("TEST_ORDER_NOT_FOUND", ErrorTypeNotfound,
+	"order {id} of {user} not found",
+	"Returned when the order does not exist")
+
+func TestCatalog(t *testing.T) {
+	err := errTestOrderNotFound.New(Params{"id": 29, "user": "mario"})
+	assert.Equal(t, "order 29 of mario not found", err.Error())
+	assert.Equal(t, "TEST_ORDER_NOT_FOUND", err.Reason())
+	assert.Equal(t, ErrorTypeNotfound, err.Type())
+	assert.NotEmpty(t, err.StackTrace())
+
+	t.Run("errors.Is should match by reason", func(t *testing.T) {
+		assert.True(t, stderrors.Is(Wrap(err, "cannot checkout"), errTestOrderNotFound))
+		assert.False(t, stderrors.Is(New(ErrorTypeNotfound, "order not found"), errTestOrderNotFound))
+		assert.True(t, stderrors.Is(err, New(ErrorTypeNotfound, "")))
+	})
+
+	t.Run("reason and params should be carried over gRPC", func(t *testing.T) {
+		decoded, ok := FromGRPCError(ToGRPCError(err).Err())
+		assert.True(t, ok)
+		assert.Equal(t, "TEST_ORDER_NOT_FOUND", decoded.Reason())
+		assert.Equal(t, Params{"id": "29", "user": "mario"}, decoded.GetParams())
+		assert.True(t, stderrors.Is(decoded, errTestOrderNotFound))
+	})
+
+	t.Run("reason should be carried over JSON", func(t *testing.T) {
+		b, _ := json.Marshal(err)
+		var decoded Error
+		assert.NoError(t, json.Unmarshal(b, &decoded))
+		assert.Equal(t, "TEST_ORDER_NOT_FOUND", decoded.Reason())
+	})
+
+	t.Run("missing params should be kept as placeholder", func(t *testing.T) {
+		assert.Equal(t, "order {id} of mario not found", errTestOrderNotFound.New(Params{"user": "mario"}).Error())
+		assert.Equal(t, []string{"id", "user"}, errTestOrderNotFound.Params())
+	})
+
+	t.Run("duplicated reason should panic", func(t *testing.T) {
+		assert.Panics(t, func() {
+			Define("TEST_ORDER_NOT_FOUND", ErrorTypeNotfound, "", "")
+		})
+	})
+
+	t.Run("dump", func(t *testing.T) {
+		var b = bytes.NewBuffer(nil)
+		assert.NoError(t, WriteCatalogJSON(b))
+		var entries []map[string]interface{}
+		assert.NoError(t, json.Unmarshal(b.Bytes(), &entries))
+		var found bool
+		for _, e := range entries {
+			if e["reason"] == "TEST_ORDER_NOT_FOUND" {
+				found = true
+				assert.Equal(t, "NotFound", e["code"])
+				assert.EqualValues(t, 404, e["httpStatus"])
+			}
+		}
+		assert.True(t, found)
+
+		b.Reset()
+		assert.NoError(t, WriteCatalogMarkdown(b))
+		assert.Contains(t, b.String(), "| `TEST_ORDER_NOT_FOUND` | NotFound | 404 | order {id} of {user} not found | Returned when the order does not exist |")
+	})
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+// Package catalogcmd dump error catalog for API documentation,
+// since definitions are registered by service packages, each service
+// has its own command which import those packages and call Main
+// see examples/stringsvc/cmd/errcatalog
+package catalogcmd
+
+import (
+	"flag"
+	"fmt"
+	"github.com/octofoxio/foundation/errors"
+	"io"
+	"os"
+)
+
+// Run parse args (-format json|markdown, -o output file)
+// and write the catalog to w when no output file provided
+func Run(args []string, w io.Writer) error {
+	var flags = flag.NewFlagSet("errcatalog", flag.ContinueOnError)
+	var (
+		format = flags.String("format", "json", "output format, json or markdown")
+		output = flags.String("o", "", "output file, default to stdout")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		w = f
+	}
+
+	switch *format {
+	case "json":
+		return errors.WriteCatalogJSON(w)
+	case "markdown", "md":
+		return errors.WriteCatalogMarkdown(w)
+	}
+	return fmt.Errorf("unknown format %s", *format)
+}
+
+func Main() {
+	if err := Run(os.Args[1:], os.Stdout); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	"google.golang.org/grpc/codes"
+	"net/http"
+)
+
+// canonical ErrorType => gRPC code
+var typeToCode = map[ErrorType]codes.Code{
+	ErrorTypeBadInput:           codes.InvalidArgument,
+	ErrorTypeAuth:               codes.Unauthenticated,
+	ErrorTypeForbidden:          codes.PermissionDenied,
+	ErrorTypeNotfound:           codes.NotFound,
+	ErrorTypeConflict:           codes.AlreadyExists,
+	ErrorTypePreconditionFailed: codes.FailedPrecondition,
+	ErrorTypeTooManyRequests:    codes.ResourceExhausted,
+	ErrorTypeCanceled:           codes.Canceled,
+	ErrorTypeInternal:           codes.Internal,
+	ErrorTypeNotImplemented:     codes.Unimplemented,
+	ErrorTypeUnavailable:        codes.Unavailable,
+	ErrorTypeTimeout:            codes.DeadlineExceeded,
+}
+
+// every gRPC code => closest ErrorType
+var codeToType = map[codes.Code]ErrorType{
+	codes.Canceled:           ErrorTypeCanceled,
+	codes.Unknown:            ErrorTypeInternal,
+	codes.InvalidArgument:    ErrorTypeBadInput,
+	codes.DeadlineExceeded:   ErrorTypeTimeout,
+	codes.NotFound:           ErrorTypeNotfound,
+	codes.AlreadyExists:      ErrorTypeConflict,
+	codes.PermissionDenied:   ErrorTypeForbidden,
+	codes.ResourceExhausted:  ErrorTypeTooManyRequests,
+	codes.FailedPrecondition: ErrorTypePreconditionFailed,
+	codes.Aborted:            ErrorTypeConflict,
+	codes.OutOfRange:         ErrorTypeBadInput,
+	codes.Unimplemented:      ErrorTypeNotImplemented,
+	codes.Internal:           ErrorTypeInternal,
+	codes.Unavailable:        ErrorTypeUnavailable,
+	codes.DataLoss:           ErrorTypeInternal,
+	codes.Unauthenticated:    ErrorTypeAuth,
+}
+
+// CodeFromType map ErrorType to gRPC code, ErrorType
+// which is not in the table is mapped by its HTTP status class
+func CodeFromType(t ErrorType) codes.Code {
+	if c, ok := typeToCode[t]; ok {
+		return c
+	}
+	switch {
+	case t == http.StatusRequestTimeout:
+		return codes.DeadlineExceeded
+	case t == http.StatusMethodNotAllowed:
+		return codes.Unimplemented
+	case t == http.StatusRequestedRangeNotSatisfiable:
+		return codes.OutOfRange
+	case t >= 400 && t < 500:
+		return codes.InvalidArgument
+	}
+	return codes.Internal
+}
+
+// TypeFromCode map gRPC code to ErrorType
+func TypeFromCode(c codes.Code) ErrorType {
+	if t, ok := codeToType[c]; ok {
+		return t
+	}
+	return ErrorTypeInternal
+}
+
+// Code return gRPC code of err, err which is not (or does not wrap)
+// *Error or gRPC status is internal, nil is codes.OK
+func Code(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	return From(err).Code()
+}
+
+// HTTPStatusFromCode map gRPC code to HTTP status code
+func HTTPStatusFromCode(c codes.Code) int {
+	if c == codes.OK {
+		return http.StatusOK
+	}
+	return TypeFromCode(c).HTTPStatus()
+}
+
+// TypeFromHTTPStatus map HTTP status code (4xx, 5xx) to ErrorType
+func TypeFromHTTPStatus(status int) ErrorType {
+	if status < 400 || status > 599 {
+		return ErrorTypeInternal
+	}
+	return ErrorType(status)
+}
+
+// HTTPStatus return HTTP status code of ErrorType
+func (t ErrorType) HTTPStatus() int {
+	if t < 400 || t > 599 {
+		return http.StatusInternalServerError
+	}
+	return int(t)
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"testing"
+)
+
+func TestCodeMapping(t *testing.T) {
+	t.Run("every ErrorType should map back to itself", func(t *testing.T) {
+		for errorType, code := range typeToCode {
+			assert.Equal(t, errorType, TypeFromCode(code), code.String())
+		}
+	})
+
+	t.Run("every gRPC code should have ErrorType", func(t *testing.T) {
+		for c := codes.Canceled; c <= codes.Unauthenticated; c++ {
+			_, ok := codeToType[c]
+			assert.True(t, ok, c.String())
+		}
+	})
+
+	t.Run("ToGRPCError should use the table", func(t *testing.T) {
+		assert.Equal(t, codes.PermissionDenied, ToGRPCError(New(ErrorTypeForbidden, "")).Code())
+		assert.Equal(t, codes.Unauthenticated, ToGRPCError(New(ErrorTypeAuth, "")).Code())
+		assert.Equal(t, codes.NotFound, ToGRPCError(New(ErrorTypeNotfound, "")).Code())
+		assert.Equal(t, codes.ResourceExhausted, ToGRPCError(New(ErrorTypeTooManyRequests, "")).Code())
+		assert.Equal(t, codes.InvalidArgument, ToGRPCError(New(http.StatusUnprocessableEntity, "")).Code())
+		assert.Equal(t, codes.Unimplemented, ToGRPCError(New(ErrorTypeMethodNotAllowed, "")).Code())
+	})
+
+	t.Run("HTTP status", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, HTTPStatusFromCode(codes.OK))
+		assert.Equal(t, http.StatusConflict, HTTPStatusFromCode(codes.Aborted))
+		assert.Equal(t, http.StatusGatewayTimeout, HTTPStatusFromCode(codes.DeadlineExceeded))
+		assert.Equal(t, http.StatusServiceUnavailable, NewWithCode(codes.Unavailable, "").HTTPStatus())
+		assert.Equal(t, http.StatusBadRequest, NewWithCode(codes.OutOfRange, "").HTTPStatus())
+		assert.Equal(t, http.StatusInternalServerError, New(ErrorType(0), "").HTTPStatus())
+		assert.Equal(t, ErrorTypeTimeout, TypeFromHTTPStatus(http.StatusGatewayTimeout))
+		assert.Equal(t, ErrorTypeInternal, TypeFromHTTPStatus(http.StatusOK))
+	})
+
+	t.Run("Code of error", func(t *testing.T) {
+		assert.Equal(t, codes.OK, Code(nil))
+		assert.Equal(t, codes.NotFound, Code(fmt.Errorf("get user: %w", New(ErrorTypeNotfound, ""))))
+		assert.Equal(t, codes.Unavailable, Code(status.Error(codes.Unavailable, "")))
+		assert.Equal(t, codes.Internal, Code(stderrors.New("boom")))
+	})
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	stderrors "errors"
+	"github.com/golang/protobuf/ptypes"
+	foundationerrorv2 "github.com/octofoxio/foundation/errors/v2"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// FromV2 convert foundationerrorv2.Error, gRPC code
+// and every message lines are preserved, status details
+// attached to v2 error are decoded as well
+func FromV2(err *foundationerrorv2.Error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e, ok = fromStatusDetails(err.Status())
+	if !ok {
+		e = NewWithCode(err.Type, err.Error())
+		e.extraDetails = extraStatusDetails(err.Status())
+	}
+	e.cause = err.Unwrap()
+	if pcs := err.StackTrace(); len(pcs) > 0 {
+		var s stack = pcs
+		e.stack = &s
+	}
+	return e
+}
+
+// ToV2 convert error to foundationerrorv2.Error for services
+// which still use v2, everything else than code and message
+// is carried as status details
+func (g *Error) ToV2() *foundationerrorv2.Error {
+	var result = foundationerrorv2.New(g.Code())
+	if g.message != "" {
+		for _, line := range strings.Split(g.message, "\n") {
+			result.AppendMessage("%s", line)
+		}
+	}
+	result = result.WithDetails(g.statusDetails()...)
+	for _, detail := range g.extraDetails {
+		// detail which type is not registered cannot be carried by v2
+		var d ptypes.DynamicAny
+		if err := ptypes.UnmarshalAny(detail, &d); err == nil {
+			result = result.WithDetails(d.Message)
+		}
+	}
+	return result
+}
+
+// From convert any error into *Error
+// - *Error (or error which wrap it) is returned as-is
+// - *foundationerrorv2.Error is converted by FromV2
+// - *MultiError is converted by ToError
+// - gRPC status error is decoded by FromGRPCError or keep its code and message
+// - other errors become internal error with err as a cause
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := asError(err); ok {
+		return e
+	}
+	var e *Error
+	if st, ok := status.FromError(err); ok {
+		if e, ok := FromGRPCError(err); ok {
+			return e
+		}
+		e = NewWithCode(st.Code(), st.Message())
+		e.extraDetails = extraStatusDetails(st)
+	} else {
+		e = New(ErrorTypeInternal, err.Error())
+	}
+	e.cause = err
+	e.stack = callers(1)
+	return e
+}
+
+// asError find the outer most foundation error in err chain
+func asError(err error) (*Error, bool) {
+	for ; err != nil; err = stderrors.Unwrap(err) {
+		switch e := err.(type) {
+		case *Error:
+			return e, true
+		case *MultiError:
+			return e.ToError(), true
+		case *foundationerrorv2.Error:
+			return FromV2(e), true
+		}
+	}
+	return nil, false
+}
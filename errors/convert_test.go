@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	foundationerrorv2 "github.com/octofoxio/foundation/errors/v2"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestFromV2(t *testing.T) {
+	v2 := foundationerrorv2.New(codes.AlreadyExists).
+		AppendMessage("user %s already exists", "mario").
+		AppendMessage("please login instead")
+
+	err := FromV2(v2)
+	assert.Equal(t, codes.AlreadyExists, err.Code())
+	assert.Equal(t, "user mario already exists\nplease login instead", err.Error())
+
+	back := err.ToV2()
+	assert.Equal(t, v2.Type, back.Type)
+	assert.Equal(t, v2.Error(), back.Error())
+
+	t.Run("gRPC code should survive gRPC transport", func(t *testing.T) {
+		decoded, ok := FromGRPCError(ToGRPCError(err).Err())
+		assert.True(t, ok)
+		assert.Equal(t, codes.AlreadyExists, decoded.Code())
+		assert.Equal(t, err.Error(), decoded.Error())
+	})
+}
+
+func TestFrom(t *testing.T) {
+	original := New(ErrorTypeNotfound, "not found").WithDetail("user")
+	assert.Equal(t, original, From(original))
+	assert.Nil(t, From(nil))
+
+	fromV2 := From(foundationerrorv2.New(codes.PermissionDenied).AppendMessage("denied"))
+	assert.EqualValues(t, ErrorTypeForbidden, fromV2.Type())
+	assert.Equal(t, codes.PermissionDenied, fromV2.Code())
+
+	fromStatus := From(status.Error(codes.Unavailable, "try again"))
+	assert.Equal(t, codes.Unavailable, fromStatus.Code())
+	assert.Equal(t, "try again", fromStatus.Error())
+
+	cause := fmt.Errorf("connection refused")
+	generic := From(cause)
+	assert.EqualValues(t, ErrorTypeInternal, generic.Type())
+	assert.Equal(t, codes.Internal, generic.Code())
+	assert.Equal(t, cause, generic.Cause())
+}
+
+func TestWithCode(t *testing.T) {
+	err := New(ErrorTypeBadInput, "bad").WithCode(codes.OutOfRange)
+	assert.Equal(t, codes.OutOfRange, err.Code())
+	assert.EqualValues(t, ErrorTypeBadInput, err.Type())
+	assert.Equal(t, codes.InvalidArgument, err.WithType(ErrorTypeBadInput).Code())
+}
+
+func TestUnknownStatusDetails(t *testing.T) {
+	var quota = &errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{Subject: "user:1", Description: "daily limit"}}}
+	var precondition = &errdetails.PreconditionFailure{Violations: []*errdetails.PreconditionFailure_Violation{{Type: "TOS", Subject: "user:1"}}}
+	var detailsOf = func(st *status.Status) []interface{} {
+		var result []interface{}
+		for _, d := range st.Details() {
+			switch d.(type) {
+			case *errdetails.QuotaFailure, *errdetails.PreconditionFailure:
+				result = append(result, d)
+			}
+		}
+		return result
+	}
+
+	t.Run("status without foundation details", func(t *testing.T) {
+		st, _ := status.New(codes.ResourceExhausted, "quota exceeded").WithDetails(quota)
+		e := From(st.Err())
+		assert.Equal(t, ErrorTypeTooManyRequests, e.Type())
+		assert.Len(t, detailsOf(ToGRPCError(e)), 1)
+		assert.True(t, proto.Equal(quota, detailsOf(ToGRPCError(e))[0].(proto.Message)))
+	})
+
+	t.Run("status with foundation details", func(t *testing.T) {
+		st := ToGRPCError(New(ErrorTypePreconditionFailed, "accept TOS first").WithReason("TOS_REQUIRED"))
+		st, _ = st.WithDetails(precondition)
+		e := From(st.Err())
+		assert.Equal(t, "TOS_REQUIRED", e.Reason())
+		assert.Len(t, detailsOf(ToGRPCError(e)), 1)
+		assert.Len(t, detailsOf(ToGRPCError(Wrap(e, "check"))), 1, "wrapper keep details of cause")
+	})
+
+	t.Run("v2 details", func(t *testing.T) {
+		v2 := foundationerrorv2.New(codes.FailedPrecondition).AppendMessage("accept TOS first").WithDetails(precondition)
+		e := FromV2(v2)
+		assert.Len(t, detailsOf(ToGRPCError(e)), 1)
+		assert.Len(t, detailsOf(e.ToV2().Status()), 1)
+	})
+}
@@ -6,46 +6,78 @@ package errors
 
 import (
 	"encoding/json"
+	"github.com/golang/protobuf/ptypes/any"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"time"
 )
 
 type ErrorType int
 
+// ErrorType is HTTP status code of error
+// see code.go for mapping to gRPC codes
 const (
-	ErrorTypeAuth      ErrorType = 401
-	ErrorTypeBadInput            = 400
-	ErrorTypeInternal            = 500
-	ErrorTypeForbidden           = 403
-	ErrorTypeNotfound            = 404
+	ErrorTypeAuth               ErrorType = 401
+	ErrorTypeBadInput           ErrorType = 400
+	ErrorTypeInternal           ErrorType = 500
+	ErrorTypeForbidden          ErrorType = 403
+	ErrorTypeNotfound           ErrorType = 404
+	ErrorTypeMethodNotAllowed   ErrorType = 405
+	ErrorTypeConflict           ErrorType = 409
+	ErrorTypePreconditionFailed ErrorType = 412
+	ErrorTypeTooManyRequests    ErrorType = 429
+	ErrorTypeCanceled           ErrorType = 499
+	ErrorTypeNotImplemented     ErrorType = 501
+	ErrorTypeUnavailable        ErrorType = 503
+	ErrorTypeTimeout            ErrorType = 504
 )
 
 type Error struct {
-	code    ErrorType
-	message string
-	detail  []string
-	debug   interface{}
-	stack   interface{}
+	code            ErrorType
+	status          codes.Code // explicit gRPC code, codes.OK mean derive from code
+	reason          string
+	message         string
+	params          Params
+	detail          []string
+	fieldViolations []FieldViolation
+	retryDelay      time.Duration
+	localized       *LocalizedMessage
+	debug           interface{}
+	stack           *stack
+	cause           error
+	extraDetails    []*any.Any // status details which are not decoded, sent back as-is
 }
 
 func (g *Error) UnmarshalJSON(b []byte) error {
 	var d struct {
 		Code    ErrorType `json:"code"`
+		Reason  string    `json:"reason"`
 		Message string    `json:"message"`
 		Details []string  `json:"details"`
+
+		LocalizedMessage *LocalizedMessage `json:"localizedMessage"`
 	}
 	err := json.Unmarshal(b, &d)
 	g.code = d.Code
 	g.message = d.Message
 	g.detail = d.Details
+	g.localized = d.LocalizedMessage
+	if d.Reason != reasonFromCode(CodeFromType(d.Code)) {
+		g.reason = d.Reason
+	}
 	return err
 }
 func (g *Error) MarshalJSON() (b []byte, err error) {
-	b, err = json.Marshal(map[string]interface{}{
+	var data = map[string]interface{}{
 		"code":    g.code,
+		"reason":  g.Reason(),
 		"message": g.message,
 		"details": g.detail,
-	})
+	}
+	if g.localized != nil {
+		data["localizedMessage"] = g.localized
+	}
+	b, err = json.Marshal(data)
 	return
 }
 
@@ -53,6 +85,15 @@ func (g *Error) Type() ErrorType {
 	return g.code
 }
 
+// HTTPStatus return HTTP status code of this error,
+// explicit gRPC code take precedence over ErrorType
+func (g *Error) HTTPStatus() int {
+	if g.status != codes.OK {
+		return HTTPStatusFromCode(g.status)
+	}
+	return g.code.HTTPStatus()
+}
+
 func (g *Error) Error() string {
 	return g.message
 }
@@ -61,9 +102,11 @@ func (g *Error) GetDetail() []string {
 	return g.detail
 }
 
+// GetDebug return debug data, or the stack
+// which captured at creation when debug is not provided
 func (g *Error) GetDebug() interface{} {
-	if g.debug == nil {
-		return g.stack
+	if g.debug == nil && g.stack != nil {
+		return g.stack.entries()
 	}
 	return g.debug
 }
@@ -80,6 +123,99 @@ func (g Error) WithDebug(d interface{}) *Error {
 
 func (g Error) WithType(t ErrorType) *Error {
 	g.code = t
+	g.status = codes.OK
+	return &g
+}
+
+// Code return gRPC code of this error, explicit code
+// from WithCode (or converted from v2/gRPC status) take precedence
+func (g *Error) Code() codes.Code {
+	if g.status != codes.OK {
+		return g.status
+	}
+	return CodeFromType(g.code)
+}
+
+func (g Error) WithCode(c codes.Code) *Error {
+	g.status = c
+	g.code = TypeFromCode(c)
+	return &g
+}
+
+// Reason is a machine readable identifier of the error (e.g. USER_NOT_FOUND)
+// it default to gRPC code name in upper snake case
+func (g *Error) Reason() string {
+	if g.reason != "" {
+		return g.reason
+	}
+	return reasonFromCode(g.Code())
+}
+
+func (g Error) WithReason(reason string) *Error {
+	g.reason = reason
+	return &g
+}
+
+// GetParams return values which used to render message
+// of error created from catalog Definition
+func (g *Error) GetParams() Params {
+	return g.params
+}
+
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+func (g *Error) GetFieldViolations() []FieldViolation {
+	return g.fieldViolations
+}
+
+func (g Error) WithFieldViolation(field, description string) *Error {
+	g.fieldViolations = append(append([]FieldViolation{}, g.fieldViolations...), FieldViolation{
+		Field:       field,
+		Description: description,
+	})
+	return &g
+}
+
+// GetRetryDelay return how long client should wait before retry,
+// zero mean the request should not be retried
+func (g *Error) GetRetryDelay() time.Duration {
+	return g.retryDelay
+}
+
+func (g Error) WithRetryDelay(d time.Duration) *Error {
+	g.retryDelay = d
+	return &g
+}
+
+type LocalizedMessage struct {
+	Locale  string `json:"locale"`
+	Message string `json:"message"`
+}
+
+func (g *Error) GetLocalizedMessage() *LocalizedMessage {
+	return g.localized
+}
+
+func (g Error) WithLocalizedMessage(locale, message string) *Error {
+	g.localized = &LocalizedMessage{Locale: locale, Message: message}
+	return &g
+}
+
+// GRPCStatus allow gRPC server to convert *Error
+// returned from handler into status with details
+func (g *Error) GRPCStatus() *status.Status {
+	return ToGRPCError(g)
+}
+
+func (g *Error) Cause() error {
+	return g.cause
+}
+
+func (g Error) WithCause(err error) *Error {
+	g.cause = err
 	return &g
 }
 
@@ -89,61 +225,82 @@ func NewGlobErr(t ErrorType, message string) *Error {
 	return &Error{
 		code:    t,
 		message: message,
+		stack:   callers(1),
 	}
 }
 
+// New create error and capture the caller stack,
+// use NewWithoutStack in hot paths
 func New(t ErrorType, message string) *Error {
 	return &Error{
 		code:    t,
 		message: message,
+		stack:   callers(1),
 	}
 }
 
-func ToGRPCError(err *Error) *status.Status {
-	type data struct {
-		ErrorType ErrorType   `json:"code"`
-		Message   string      `json:"message"`
-		Detail    []string    `json:"detail,omitempty"`
-		Debug     interface{} `json:"debug,omitempty"`
+// NewWithoutStack create error without capture stack,
+// which is cheaper for errors that used as control flow
+func NewWithoutStack(t ErrorType, message string) *Error {
+	return &Error{
+		code:    t,
+		message: message,
 	}
-	b, _ := json.Marshal(&data{
-		Message:   err.message,
-		Debug:     err.debug,
-		Detail:    err.detail,
-		ErrorType: err.code,
-	})
+}
+
+// NewWithCode create error from gRPC code
+func NewWithCode(c codes.Code, message string) *Error {
+	return &Error{
+		code:    TypeFromCode(c),
+		status:  c,
+		message: message,
+		stack:   callers(1),
+	}
+}
 
-	// handle Glob Error แล้วเปลี่ยนเป็น GRPC error
-	// โดยจะต้องทำให้ error สามารถแปลงกลับไปเป็น GlobError ได้
-	// ด้วยการจะแนบ JSON ไปใน message
-	switch err.Type() {
-	case ErrorTypeBadInput:
-		return status.New(codes.InvalidArgument, string(b))
-	case ErrorTypeForbidden:
-	case ErrorTypeAuth:
-		return status.New(codes.PermissionDenied, string(b))
+// ToGRPCError convert error to gRPC status, the message is kept
+// human readable and everything else travel as google.rpc.Status details
+// (ErrorInfo, BadRequest, RetryInfo, DebugInfo and LocalizedMessage)
+func ToGRPCError(err *Error) *status.Status {
+	st := status.New(err.Code(), err.message)
+	if withDetails, e := st.WithDetails(err.statusDetails()...); e == nil {
+		st = withDetails
 	}
-	return status.New(codes.Internal, string(b))
+	if len(err.extraDetails) > 0 {
+		// details which are not known by this package (e.g. QuotaFailure)
+		p := st.Proto()
+		p.Details = append(p.Details, err.extraDetails...)
+		st = status.FromProto(p)
+	}
+	return st
 }
 
+// FromGRPCError convert gRPC status error back to *Error
+// it support both status details and the legacy format
+// which packed error as JSON in status message
 func FromGRPCError(err error) (*Error, bool) {
-	if st, ok := status.FromError(err); ok {
-		var data struct {
-			ErrorType ErrorType   `json:"code"`
-			Message   string      `json:"message"`
-			Detail    []string    `json:"detail,omitempty"`
-			Debug     interface{} `json:"debug,omitempty"`
-		}
-		if err := json.Unmarshal([]byte(st.Message()), &data); err != nil {
-			return nil, false
-		}
-		return &Error{
-			code:    data.ErrorType,
-			detail:  data.Detail,
-			debug:   data.Debug,
-			message: data.Message,
-		}, true
-	} else {
+	st, ok := status.FromError(err)
+	if !ok || st == nil {
 		return nil, false
 	}
+	if e, ok := fromStatusDetails(st); ok {
+		return e, true
+	}
+
+	// legacy format
+	var data struct {
+		ErrorType ErrorType   `json:"code"`
+		Message   string      `json:"message"`
+		Detail    []string    `json:"detail,omitempty"`
+		Debug     interface{} `json:"debug,omitempty"`
+	}
+	if err := json.Unmarshal([]byte(st.Message()), &data); err != nil {
+		return nil, false
+	}
+	return &Error{
+		code:    data.ErrorType,
+		detail:  data.Detail,
+		debug:   data.Debug,
+		message: data.Message,
+	}, true
 }
@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MessageSource is where localized message files are loaded from,
+// foundation.FileSystem (local or statik bundle) satisfy this interface
+type MessageSource interface {
+	GetObject(key string) ([]byte, error)
+}
+
+// Localizer render error message in client locale, messages are
+// templates keyed by error reason and use the same {name} placeholders
+// as catalog Definition
+type Localizer struct {
+	defaultLocale string
+	mux           *sync.RWMutex
+	messages      map[string]map[string]string
+}
+
+func NewLocalizer(defaultLocale string) *Localizer {
+	return &Localizer{
+		defaultLocale: normalizeLocale(defaultLocale),
+		mux:           &sync.RWMutex{},
+		messages:      map[string]map[string]string{},
+	}
+}
+
+// AddMessages add message templates of locale, keyed by reason
+func (l *Localizer) AddMessages(locale string, messages map[string]string) {
+	locale = normalizeLocale(locale)
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.messages[locale] == nil {
+		l.messages[locale] = map[string]string{}
+	}
+	for reason, message := range messages {
+		l.messages[locale][reason] = message
+	}
+}
+
+// Load read {dir}/{locale}.json of every locales from source
+//
+// ```
+// // locales/th.json
+// { "USER_NOT_FOUND": "ไม่พบผู้ใช้ {id}" }
+//
+// localizer.Load(foundation.NewFileSystem("./", foundation.StaticMode_Statik), "locales", "th", "en")
+// ```
+func (l *Localizer) Load(source MessageSource, dir string, locales ...string) error {
+	for _, locale := range locales {
+		b, err := source.GetObject(path.Join(dir, locale+".json"))
+		if err != nil {
+			return err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(b, &messages); err != nil {
+			return Wrapf(err, "invalid message file of locale %s", locale)
+		}
+		l.AddMessages(locale, messages)
+	}
+	return nil
+}
+
+// Message render message of err in the best locale from acceptLanguage
+// (e.g. "th-TH,th;q=0.9,en;q=0.8"), fallback to default locale
+func (l *Localizer) Message(err *Error, acceptLanguage string) (locale string, message string, ok bool) {
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+	var reason = err.Reason()
+	for _, candidate := range append(parseAcceptLanguage(acceptLanguage), l.defaultLocale) {
+		for _, locale := range []string{candidate, baseLocale(candidate)} {
+			if template, ok := l.messages[locale][reason]; ok {
+				return locale, renderTemplate(template, err.params), true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// Localize attach localized message to err, the original
+// message is kept for logging, err is returned as-is
+// when it is not (or not wrap) *Error or there is no message for its reason.
+// Error found in the chain is copied (it may be shared sentinel) and
+// the copy wrap err, so errors.Is and errors.As still find the wrappers.
+// Every items of MultiError are localized
+func (l *Localizer) Localize(err error, acceptLanguage string) error {
+	var m *MultiError
+	if stderrors.As(err, &m) {
+		m.mux.Lock()
+		for i, item := range m.items {
+			m.items[i].Err = l.Localize(item.Err, acceptLanguage)
+		}
+		m.mux.Unlock()
+		return err
+	}
+	var e *Error
+	if !stderrors.As(err, &e) {
+		return err
+	}
+	locale, message, ok := l.Message(e, acceptLanguage)
+	if !ok {
+		return err
+	}
+	var localized = e.WithLocalizedMessage(locale, message)
+	if e != err {
+		localized.cause = err
+	}
+	return localized
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(locale), "_", "-", -1))
+}
+
+func baseLocale(locale string) string {
+	return strings.SplitN(locale, "-", 2)[0]
+}
+
+// parseAcceptLanguage return locales ordered by quality
+func parseAcceptLanguage(acceptLanguage string) []string {
+	type language struct {
+		locale  string
+		quality float64
+	}
+	var languages []language
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		var fields = strings.Split(part, ";")
+		var locale = normalizeLocale(fields[0])
+		if locale == "" || locale == "*" {
+			continue
+		}
+		var quality = 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if q, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		languages = append(languages, language{locale: locale, quality: quality})
+	}
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+	var result = make([]string, 0, len(languages))
+	for _, l := range languages {
+		result = append(result, l.locale)
+	}
+	return result
+}
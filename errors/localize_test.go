@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type mapSource map[string]string
+
+type chargeError struct {
+	err error
+}
+
+func (c *chargeError) Error() string { return "charge: " + c.err.Error() }
+func (c *chargeError) Unwrap() error { return c.err }
+
+func (m mapSource) GetObject(key string) ([]byte, error) {
+	if v, ok := m[key]; ok {
+		return []byte(v), nil
+	}
+	return nil, fmt.Errorf("%s not found", key)
+}
+
+var errTestBalance = Define("TEST_INSUFFICIENT_BALANCE", ErrorTypePreconditionFailed,
+	"balance {balance} is not enough",
+	"Returned when wallet balance is lower than amount")
+
+func TestLocalizer(t *testing.T) {
+	localizer := NewLocalizer("en")
+	err := localizer.Load(mapSource{
+		"locales/th.json": `{"TEST_INSUFFICIENT_BALANCE": "ยอดเงิน {balance} บาทไม่เพียงพอ"}`,
+		"locales/en.json": `{"TEST_INSUFFICIENT_BALANCE": "Your balance ({balance} THB) is not enough"}`,
+	}, "locales", "th", "en")
+	assert.NoError(t, err)
+
+	original := errTestBalance.New(Params{"balance": 20})
+
+	t.Run("best locale from Accept-Language", func(t *testing.T) {
+		localized := localizer.Localize(original, "th-TH,th;q=0.9,en;q=0.8").(*Error)
+		assert.Equal(t, &LocalizedMessage{Locale: "th", Message: "ยอดเงิน 20 บาทไม่เพียงพอ"}, localized.GetLocalizedMessage())
+		assert.Equal(t, "balance 20 is not enough", localized.Error(), "original message should be kept")
+		assert.Nil(t, original.GetLocalizedMessage())
+
+		localized = localizer.Localize(original, "en;q=0.5, th;q=0.1").(*Error)
+		assert.Equal(t, "en", localized.GetLocalizedMessage().Locale)
+	})
+
+	t.Run("fallback to default locale", func(t *testing.T) {
+		localized := localizer.Localize(original, "ja-JP").(*Error)
+		assert.Equal(t, "Your balance (20 THB) is not enough", localized.GetLocalizedMessage().Message)
+	})
+
+	t.Run("error without message should be kept as-is", func(t *testing.T) {
+		notFound := New(ErrorTypeNotfound, "not found")
+		assert.Equal(t, notFound, localizer.Localize(notFound, "th"))
+		generic := fmt.Errorf("generic")
+		assert.Equal(t, generic, localizer.Localize(generic, "th"))
+	})
+
+	t.Run("localized message should be sent over gRPC", func(t *testing.T) {
+		localized := localizer.Localize(original, "th").(*Error)
+		decoded, ok := FromGRPCError(ToGRPCError(localized).Err())
+		assert.True(t, ok)
+		assert.Equal(t, localized.GetLocalizedMessage(), decoded.GetLocalizedMessage())
+	})
+
+	t.Run("wrapper should be kept", func(t *testing.T) {
+		var errCharge = fmt.Errorf("charge: %w", original)
+		localized := localizer.Localize(errCharge, "th")
+		assert.True(t, stderrors.Is(localized, errCharge))
+		assert.True(t, stderrors.Is(localized, errTestBalance))
+		assert.Equal(t, "th", From(localized).GetLocalizedMessage().Locale)
+		assert.Nil(t, original.GetLocalizedMessage())
+
+		var target *chargeError
+		localized = localizer.Localize(&chargeError{err: original}, "th")
+		assert.True(t, stderrors.As(localized, &target))
+		assert.Equal(t, "th", From(localized).GetLocalizedMessage().Locale)
+	})
+
+	t.Run("items of MultiError should be localized", func(t *testing.T) {
+		errs := NewMultiError("invalid wallets").
+			Append("a", original).
+			Append("b", New(ErrorTypeNotfound, "not found"))
+		localized := localizer.Localize(fmt.Errorf("batch: %w", errs), "th")
+		m, ok := AsMultiError(localized)
+		assert.True(t, ok)
+		assert.Equal(t, "th", From(m.Items()[0].Err).GetLocalizedMessage().Locale)
+		assert.Nil(t, From(m.Items()[1].Err).GetLocalizedMessage())
+		assert.Equal(t, []FieldViolation{
+			{Field: "a", Description: "ยอดเงิน 20 บาทไม่เพียงพอ"},
+			{Field: "b", Description: "not found"},
+		}, From(localized).GetFieldViolations())
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		assert.Error(t, NewLocalizer("en").Load(mapSource{"th.json": "{"}, "", "th"))
+		assert.Error(t, NewLocalizer("en").Load(mapSource{}, "", "th"))
+	})
+}
@@ -0,0 +1,327 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	multiErrorReason      = "MULTIPLE_ERRORS"
+	errorInfoPathKey      = "path"
+	errorInfoMessageKey   = "message"
+	errorInfoCodeKey      = "code"
+	defaultMultiErrorText = "multiple errors occurred"
+)
+
+// typePrecedence decide overall ErrorType of MultiError,
+// the first type found in items win, server errors come first
+// since client cannot fix them by changing the request
+var typePrecedence = []ErrorType{
+	ErrorTypeInternal,
+	ErrorTypeUnavailable,
+	ErrorTypeTimeout,
+	ErrorTypeNotImplemented,
+	ErrorTypeAuth,
+	ErrorTypeForbidden,
+	ErrorTypeTooManyRequests,
+	ErrorTypeCanceled,
+	ErrorTypeConflict,
+	ErrorTypePreconditionFailed,
+	ErrorTypeNotfound,
+	ErrorTypeBadInput,
+}
+
+// MultiErrorItem is an error of a single item in batch
+// or a single downstream call, Path locate the item
+// e.g. "[2].email" or "user-service"
+type MultiErrorItem struct {
+	Path string
+	Err  error
+}
+
+// MultiError collect errors from batch validation or fan-out calls,
+// it is safe for concurrent use and the zero value is ready to use
+//
+// ```
+// var errs = errors.NewMultiError("invalid users")
+// for i, u := range users { errs.AppendIndex(i, validate(u)) }
+// return errs.ErrorOrNil()
+// ```
+type MultiError struct {
+	message string
+	mux     sync.Mutex
+	items   []MultiErrorItem
+}
+
+func NewMultiError(message string) *MultiError {
+	return &MultiError{message: message}
+}
+
+// Append add error of item at path, nil error is ignored,
+// items of nested MultiError are flatten with path prefix
+func (m *MultiError) Append(path string, err error) *MultiError {
+	if err == nil {
+		return m
+	}
+	if nested, ok := err.(*MultiError); ok {
+		for _, item := range nested.Items() {
+			m.Append(joinPath(path, item.Path), item.Err)
+		}
+		return m
+	}
+	m.mux.Lock()
+	m.items = append(m.items, MultiErrorItem{Path: path, Err: err})
+	m.mux.Unlock()
+	return m
+}
+
+func (m *MultiError) AppendIndex(index int, err error) *MultiError {
+	return m.Append(fmt.Sprintf("[%d]", index), err)
+}
+
+func joinPath(parent, child string) string {
+	switch {
+	case parent == "":
+		return child
+	case child == "":
+		return parent
+	case strings.HasPrefix(child, "["):
+		return parent + child
+	}
+	return parent + "." + child
+}
+
+func (m *MultiError) Items() []MultiErrorItem {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return append([]MultiErrorItem{}, m.items...)
+}
+
+func (m *MultiError) Len() int {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return len(m.items)
+}
+
+// ErrorOrNil return nil when there is no error,
+// so it can be returned directly from function
+func (m *MultiError) ErrorOrNil() error {
+	if m.Len() == 0 {
+		return nil
+	}
+	return m
+}
+
+func (m *MultiError) Message() string {
+	if m.message == "" {
+		return defaultMultiErrorText
+	}
+	return m.message
+}
+
+func (m *MultiError) Error() string {
+	var items = m.Items()
+	var messages = make([]string, 0, len(items))
+	for _, item := range items {
+		if item.Path == "" {
+			messages = append(messages, item.Err.Error())
+		} else {
+			messages = append(messages, fmt.Sprintf("%s: %s", item.Path, item.Err.Error()))
+		}
+	}
+	return fmt.Sprintf("%s: %s", m.Message(), strings.Join(messages, "; "))
+}
+
+// Type return overall ErrorType, when every items has the same type
+// it is used, otherwise the one with highest precedence
+func (m *MultiError) Type() ErrorType {
+	var found = map[ErrorType]bool{}
+	for _, item := range m.Items() {
+		t := From(item.Err).Type()
+		if t >= 500 {
+			// unknown server error is treated as internal
+			if _, ok := typeToCode[t]; !ok {
+				t = ErrorTypeInternal
+			}
+		}
+		found[t] = true
+	}
+	if len(found) == 1 {
+		for t := range found {
+			return t
+		}
+	}
+	for _, t := range typePrecedence {
+		if found[t] {
+			return t
+		}
+	}
+	return ErrorTypeInternal
+}
+
+func (m *MultiError) Code() codes.Code {
+	return CodeFromType(m.Type())
+}
+
+// Is report whether any item match target, errors.Is follow
+// Unwrap() []error only since Go 1.20 so it is done here
+func (m *MultiError) Is(target error) bool {
+	for _, item := range m.Items() {
+		if stderrors.Is(item.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As find the first item which match target, the same as Is
+func (m *MultiError) As(target interface{}) bool {
+	for _, item := range m.Items() {
+		if stderrors.As(item.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap allow errors.Is and errors.As to find error in items
+func (m *MultiError) Unwrap() []error {
+	var items = m.Items()
+	var result = make([]error, 0, len(items))
+	for _, item := range items {
+		result = append(result, item.Err)
+	}
+	return result
+}
+
+// ToError convert to single *Error which each items are reported
+// as field violations, localized message of item is used when it has one
+func (m *MultiError) ToError() *Error {
+	var e = &Error{
+		code:    m.Type(),
+		reason:  multiErrorReason,
+		message: m.Message(),
+		cause:   m,
+	}
+	for _, item := range m.Items() {
+		var description = item.Err.Error()
+		if localized := From(item.Err).GetLocalizedMessage(); localized != nil {
+			description = localized.Message
+		}
+		e.fieldViolations = append(e.fieldViolations, FieldViolation{
+			Field:       item.Path,
+			Description: description,
+		})
+	}
+	return e
+}
+
+func (m *MultiError) itemDetails() []proto.Message {
+	var details []proto.Message
+	for _, item := range m.Items() {
+		e := From(item.Err)
+		details = append(details, &errdetails.ErrorInfo{
+			Reason: e.Reason(),
+			Domain: ErrorDomain,
+			Metadata: map[string]string{
+				errorInfoPathKey:    item.Path,
+				errorInfoMessageKey: e.Error(),
+				errorInfoTypeKey:    strconv.Itoa(int(e.Type())),
+				errorInfoCodeKey:    strconv.Itoa(int(e.Code())),
+			},
+		})
+	}
+	return details
+}
+
+// GRPCStatus send overall error with BadRequest field violations
+// and ErrorInfo of each item which has "path" in its metadata
+func (m *MultiError) GRPCStatus() *status.Status {
+	st := ToGRPCError(m.ToError())
+	if withDetails, err := st.WithDetails(m.itemDetails()...); err == nil {
+		return withDetails
+	}
+	return st
+}
+
+// FromGRPCMultiError decode MultiError which sent by GRPCStatus
+func FromGRPCMultiError(err error) (*MultiError, bool) {
+	st, ok := status.FromError(err)
+	if !ok || st == nil {
+		return nil, false
+	}
+	var (
+		m     = NewMultiError(st.Message())
+		found = false
+	)
+	for _, d := range st.Details() {
+		info, ok := d.(*errdetails.ErrorInfo)
+		if !ok {
+			continue
+		}
+		path, ok := info.GetMetadata()[errorInfoPathKey]
+		if !ok {
+			continue
+		}
+		found = true
+		var item = &Error{
+			reason:  info.GetReason(),
+			message: info.GetMetadata()[errorInfoMessageKey],
+		}
+		if t, err := strconv.Atoi(info.GetMetadata()[errorInfoTypeKey]); err == nil {
+			item.code = ErrorType(t)
+		}
+		if c, err := strconv.Atoi(info.GetMetadata()[errorInfoCodeKey]); err == nil && codes.Code(c) != CodeFromType(item.code) {
+			item.status = codes.Code(c)
+		}
+		if item.reason == reasonFromCode(item.Code()) {
+			item.reason = ""
+		}
+		m.Append(path, item)
+	}
+	return m, found
+}
+
+func (m *MultiError) MarshalJSON() ([]byte, error) {
+	type item struct {
+		Path    string    `json:"path"`
+		Code    ErrorType `json:"code"`
+		Reason  string    `json:"reason"`
+		Message string    `json:"message"`
+	}
+	var items []item
+	for _, i := range m.Items() {
+		e := From(i.Err)
+		items = append(items, item{
+			Path:    i.Path,
+			Code:    e.Type(),
+			Reason:  e.Reason(),
+			Message: e.Error(),
+		})
+	}
+	return json.Marshal(map[string]interface{}{
+		"code":    m.Type(),
+		"reason":  multiErrorReason,
+		"message": m.Message(),
+		"errors":  items,
+	})
+}
+
+// AsMultiError find MultiError in err chain
+func AsMultiError(err error) (*MultiError, bool) {
+	var m *MultiError
+	ok := stderrors.As(err, &m)
+	return m, ok
+}
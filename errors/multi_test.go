@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync"
+	"testing"
+)
+
+func TestMultiError(t *testing.T) {
+	var ErrMissingEmail = Define("TEST_MULTI_MISSING_EMAIL", ErrorTypeBadInput, "email is required", "")
+
+	t.Run("nil when empty", func(t *testing.T) {
+		errs := NewMultiError("invalid users")
+		errs.Append("x", nil)
+		assert.Nil(t, errs.ErrorOrNil())
+	})
+
+	t.Run("collect concurrently", func(t *testing.T) {
+		errs := NewMultiError("")
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs.AppendIndex(i, New(ErrorTypeBadInput, "invalid"))
+			}(i)
+		}
+		wg.Wait()
+		assert.Equal(t, 10, errs.Len())
+		assert.Equal(t, ErrorTypeBadInput, errs.Type())
+	})
+
+	t.Run("flatten nested path", func(t *testing.T) {
+		inner := NewMultiError("").Append("email", ErrMissingEmail.New(nil))
+		errs := NewMultiError("invalid users").AppendIndex(2, inner)
+		assert.Equal(t, "[2].email", errs.Items()[0].Path)
+		assert.Equal(t, "invalid users: [2].email: email is required", errs.Error())
+	})
+
+	t.Run("type precedence", func(t *testing.T) {
+		errs := NewMultiError("").
+			Append("a", New(ErrorTypeBadInput, "")).
+			Append("b", New(ErrorTypeNotfound, ""))
+		assert.Equal(t, ErrorTypeNotfound, errs.Type())
+
+		errs.Append("c", fmt.Errorf("plain error"))
+		assert.Equal(t, ErrorTypeInternal, errs.Type())
+		assert.Equal(t, codes.Internal, errs.Code())
+	})
+
+	t.Run("zero value", func(t *testing.T) {
+		var errs MultiError
+		assert.Nil(t, errs.ErrorOrNil())
+		errs.Append("a", ErrMissingEmail.New(nil))
+		assert.Equal(t, 1, errs.Len())
+		assert.Equal(t, "multiple errors occurred: a: email is required", errs.Error())
+	})
+
+	t.Run("Is and As find items", func(t *testing.T) {
+		errs := NewMultiError("").
+			Append("a", New(ErrorTypeNotfound, "")).
+			Append("b", Wrap(ErrMissingEmail.New(nil), "wrapped"))
+		assert.True(t, stderrors.Is(errs, ErrMissingEmail))
+
+		// without Unwrap() []error support of Go 1.20
+		assert.True(t, errs.Is(ErrMissingEmail))
+		assert.False(t, errs.Is(NewWithoutStack(ErrorTypeConflict, "conflict")))
+		var found *Error
+		assert.True(t, errs.As(&found))
+		assert.Equal(t, ErrorTypeNotfound, found.Type())
+
+		var err error = fmt.Errorf("batch: %w", errs)
+		m, ok := AsMultiError(err)
+		assert.True(t, ok)
+		assert.Equal(t, 2, m.Len())
+
+		e := From(err)
+		assert.Equal(t, "MULTIPLE_ERRORS", e.Reason())
+		assert.Len(t, e.GetFieldViolations(), 2)
+		assert.True(t, stderrors.Is(e, ErrMissingEmail))
+		assert.Equal(t, "MULTIPLE_ERRORS", Wrap(err, "wrapped").Reason())
+	})
+
+	t.Run("gRPC round trip", func(t *testing.T) {
+		errs := NewMultiError("invalid users").
+			AppendIndex(0, ErrMissingEmail.New(nil)).
+			AppendIndex(1, NewWithCode(codes.DataLoss, "corrupted"))
+
+		st, ok := status.FromError(errs)
+		assert.True(t, ok)
+		assert.Equal(t, codes.Internal, st.Code())
+		var infos int
+		for _, d := range st.Details() {
+			if _, ok := d.(*errdetails.ErrorInfo); ok {
+				infos++
+			}
+		}
+		assert.Equal(t, 3, infos)
+
+		decoded, ok := FromGRPCError(st.Err())
+		assert.True(t, ok)
+		assert.Equal(t, "MULTIPLE_ERRORS", decoded.Reason())
+		assert.Equal(t, ErrorTypeInternal, decoded.Type())
+		assert.Len(t, decoded.GetFieldViolations(), 2)
+
+		m, ok := FromGRPCMultiError(st.Err())
+		assert.True(t, ok)
+		assert.Equal(t, "invalid users", m.Message())
+		items := m.Items()
+		assert.Len(t, items, 2)
+		assert.Equal(t, "[0]", items[0].Path)
+		assert.True(t, stderrors.Is(items[0].Err, ErrMissingEmail))
+		assert.Equal(t, codes.DataLoss, From(items[1].Err).Code())
+		assert.Equal(t, "corrupted", items[1].Err.Error())
+
+		_, ok = FromGRPCMultiError(ToGRPCError(New(ErrorTypeBadInput, "single")).Err())
+		assert.False(t, ok)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		errs := NewMultiError("invalid users").Append("[0].email", ErrMissingEmail.New(nil))
+		b, err := json.Marshal(errs)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"code": 400,
+			"reason": "MULTIPLE_ERRORS",
+			"message": "invalid users",
+			"errors": [{"path": "[0].email", "code": 400, "reason": "TEST_MULTI_MISSING_EMAIL", "message": "email is required"}]
+		}`, string(b))
+	})
+}
@@ -0,0 +1,34 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	"fmt"
+)
+
+// FromPanic convert value recovered from panic to *Error,
+// *Error and foundationerrorv2.Error keep their type (so handler
+// can still panic with an error), everything else is internal error.
+// It must be called inside the deferred function, so the captured
+// stack contain the function which panic
+//
+// ```
+// if r := recover(); r != nil { err = errors.FromPanic(r) }
+// ```
+func FromPanic(r interface{}) *Error {
+	var e = &Error{
+		code:    ErrorTypeInternal,
+		message: fmt.Sprintf("panic: %v", r),
+	}
+	if err, ok := r.(error); ok {
+		if foundation, ok := asError(err); ok && foundation != nil {
+			return foundation
+		}
+		e.message = fmt.Sprintf("panic: %s", err.Error())
+		e.cause = err
+	}
+	e.stack = callers(1)
+	return e
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	foundationerrorv2 "github.com/octofoxio/foundation/errors/v2"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"testing"
+)
+
+func panicAndRecover(value interface{}) (err *Error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = FromPanic(r)
+		}
+	}()
+	panic(value)
+}
+
+func TestFromPanic(t *testing.T) {
+	t.Run("string", func(t *testing.T) {
+		err := panicAndRecover("boom")
+		assert.Equal(t, ErrorTypeInternal, err.Type())
+		assert.Equal(t, "panic: boom", err.Error())
+		assert.Contains(t, fmt.Sprintf("%+v", err), "panicAndRecover")
+	})
+
+	t.Run("runtime error", func(t *testing.T) {
+		err := func() (err *Error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = FromPanic(r)
+				}
+			}()
+			var m map[string]int
+			m["x"] = 1
+			return nil
+		}()
+		assert.Equal(t, ErrorTypeInternal, err.Type())
+		assert.Contains(t, err.Error(), "nil map")
+		assert.NotNil(t, err.Unwrap())
+	})
+
+	t.Run("foundation error keep its type", func(t *testing.T) {
+		notFound := New(ErrorTypeNotfound, "user not found")
+		assert.Equal(t, notFound, panicAndRecover(notFound))
+
+		err := panicAndRecover(foundationerrorv2.New(codes.PermissionDenied).AppendMessage("denied"))
+		assert.Equal(t, ErrorTypeForbidden, err.Type())
+
+		err = panicAndRecover(fmt.Errorf("wrapped: %w", notFound))
+		assert.True(t, stderrors.Is(err, notFound))
+	})
+
+	t.Run("other values", func(t *testing.T) {
+		err := panicAndRecover(42)
+		assert.Equal(t, "panic: 42", err.Error())
+		assert.NotEmpty(t, err.StackTrace())
+	})
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+const GenericInternalMessage = "internal server error"
+
+// Sanitize return copy of error which is safe to send to external caller,
+// debug data, stack and cause are removed. Message of server error (5xx)
+// may contain internal information (e.g. SQL or host name) so it is replaced
+// with generic message which contain requestID for reference in logs.
+// Other details (e.g. QuotaFailure from upstream service) are kept as-is
+func (g *Error) Sanitize(requestID string) *Error {
+	var e = &Error{
+		code:            g.code,
+		status:          g.status,
+		reason:          g.reason,
+		message:         g.message,
+		params:          g.params,
+		detail:          g.detail,
+		fieldViolations: g.fieldViolations,
+		retryDelay:      g.retryDelay,
+		localized:       g.localized,
+	}
+	for _, d := range g.extraDetails {
+		if !ptypes.Is(d, &errdetails.DebugInfo{}) {
+			e.extraDetails = append(e.extraDetails, d)
+		}
+	}
+	if e.HTTPStatus() >= 500 {
+		e.message = genericMessage(requestID)
+	}
+	return e
+}
+
+func genericMessage(requestID string) string {
+	if requestID == "" {
+		return GenericInternalMessage
+	}
+	return fmt.Sprintf("%s (request ID: %s)", GenericInternalMessage, requestID)
+}
+
+// Sanitize convert err by From and sanitize it,
+// MultiError keep its sanitized items (as field violations for server error)
+func Sanitize(err error, requestID string) error {
+	if err == nil {
+		return nil
+	}
+	if m, ok := err.(*MultiError); ok {
+		var result = NewMultiError(m.message)
+		for _, item := range m.Items() {
+			result.Append(item.Path, From(item.Err).Sanitize(requestID))
+		}
+		if result.Type() < 500 {
+			return result
+		}
+		err = result
+	}
+	return From(err).Sanitize(requestID)
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	t.Run("internal error", func(t *testing.T) {
+		err := Wrap(fmt.Errorf("dial tcp 10.0.0.12:5432: connection refused"), "query user").
+			WithDetail("database is in maintenance").
+			WithDebug("secret")
+		sanitized := err.Sanitize("request-1")
+		assert.Equal(t, ErrorTypeInternal, sanitized.Type())
+		assert.Equal(t, "internal server error (request ID: request-1)", sanitized.Error())
+		assert.Equal(t, []string{"database is in maintenance"}, sanitized.GetDetail(), "only message is replaced")
+		assert.Nil(t, sanitized.GetDebug())
+		assert.Nil(t, sanitized.Unwrap())
+		assert.Equal(t, "query user", err.Error(), "original error must not be changed")
+
+		for _, d := range ToGRPCError(sanitized).Details() {
+			_, isDebug := d.(*errdetails.DebugInfo)
+			assert.False(t, isDebug)
+		}
+	})
+
+	t.Run("client error keep message", func(t *testing.T) {
+		err := New(ErrorTypeBadInput, "invalid email").
+			WithReason("INVALID_EMAIL").
+			WithFieldViolation("email", "must be an email")
+		sanitized := err.Sanitize("request-1")
+		assert.Equal(t, "invalid email", sanitized.Error())
+		assert.Equal(t, "INVALID_EMAIL", sanitized.Reason())
+		assert.Equal(t, err.GetFieldViolations(), sanitized.GetFieldViolations())
+		assert.Nil(t, sanitized.GetDebug(), "stack must be removed")
+	})
+
+	t.Run("explicit server code", func(t *testing.T) {
+		sanitized := NewWithCode(codes.DataLoss, "disk /dev/sda1 corrupted").Sanitize("")
+		assert.Equal(t, codes.DataLoss, sanitized.Code())
+		assert.Equal(t, GenericInternalMessage, sanitized.Error())
+	})
+
+	t.Run("multi error", func(t *testing.T) {
+		errs := NewMultiError("invalid users").AppendIndex(0, New(ErrorTypeBadInput, "invalid email"))
+		sanitized, ok := Sanitize(errs, "request-1").(*MultiError)
+		assert.True(t, ok)
+		assert.Equal(t, "invalid users: [0]: invalid email", sanitized.Error())
+
+		errs.AppendIndex(1, fmt.Errorf("timeout"))
+		sanitized5xx := From(Sanitize(errs, "request-1"))
+		assert.Equal(t, "internal server error (request ID: request-1)", sanitized5xx.Error())
+		assert.Equal(t, []FieldViolation{
+			{Field: "[0]", Description: "invalid email"},
+			{Field: "[1]", Description: "internal server error (request ID: request-1)"},
+		}, sanitized5xx.GetFieldViolations())
+		assert.Nil(t, Sanitize(nil, ""))
+	})
+
+	t.Run("custom details should be kept", func(t *testing.T) {
+		st, _ := status.New(codes.ResourceExhausted, "quota exceeded").WithDetails(
+			&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{Subject: "user:1", Description: "daily limit"}}},
+			&errdetails.DebugInfo{Detail: "upstream stack"},
+		)
+		sanitized := ToGRPCError(From(st.Err()).Sanitize("request-1"))
+		assert.Equal(t, "quota exceeded", sanitized.Message())
+		var quota *errdetails.QuotaFailure
+		for _, d := range sanitized.Details() {
+			_, isDebug := d.(*errdetails.DebugInfo)
+			assert.False(t, isDebug)
+			if q, ok := d.(*errdetails.QuotaFailure); ok {
+				quota = q
+			}
+		}
+		assert.NotNil(t, quota)
+		assert.Equal(t, "daily limit", quota.Violations[0].Description)
+	})
+}
@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+const maxStackDepth = 32
+
+type stack []uintptr
+
+// callers capture stack of the function which call
+// error constructor, skip is number of constructor frames
+func callers(skip int) *stack {
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+	var s stack = pcs[:n]
+	return &s
+}
+
+func (s *stack) entries() []string {
+	var (
+		result = make([]string, 0, len(*s))
+		frames = runtime.CallersFrames(*s)
+	)
+	for {
+		frame, more := frames.Next()
+		result = append(result, fmt.Sprintf("%s\n\t%s:%d", frame.Function, frame.File, frame.Line))
+		if !more {
+			break
+		}
+	}
+	return result
+}
+
+func (s *stack) format(w io.Writer) {
+	for _, entry := range s.entries() {
+		_, _ = fmt.Fprintf(w, "\n%s", entry)
+	}
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrorDomain is used as ErrorInfo domain of every foundation error
+const ErrorDomain = "foundation.octofox.io"
+
+const (
+	errorInfoTypeKey         = "type"
+	errorInfoDetailKeyPrefix = "detail."
+	errorInfoParamKeyPrefix  = "param."
+)
+
+// reasonFromCode convert gRPC code name to upper snake case
+// e.g. codes.NotFound => NOT_FOUND
+func reasonFromCode(c codes.Code) string {
+	var b strings.Builder
+	for i, r := range c.String() {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteRune('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+func (g *Error) statusDetails() []proto.Message {
+	var metadata = map[string]string{
+		errorInfoTypeKey: strconv.Itoa(int(g.code)),
+	}
+	for i, d := range g.detail {
+		metadata[fmt.Sprintf("%s%d", errorInfoDetailKeyPrefix, i)] = d
+	}
+	for k, v := range g.params {
+		metadata[errorInfoParamKeyPrefix+k] = fmt.Sprint(v)
+	}
+	var details = []proto.Message{
+		&errdetails.ErrorInfo{
+			Reason:   g.Reason(),
+			Domain:   ErrorDomain,
+			Metadata: metadata,
+		},
+	}
+
+	if len(g.fieldViolations) > 0 {
+		var badRequest = &errdetails.BadRequest{}
+		for _, v := range g.fieldViolations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Description,
+			})
+		}
+		details = append(details, badRequest)
+	}
+
+	if g.retryDelay > 0 {
+		details = append(details, &errdetails.RetryInfo{
+			RetryDelay: ptypes.DurationProto(g.retryDelay),
+		})
+	}
+
+	if g.debug != nil || g.stack != nil {
+		var debugInfo = &errdetails.DebugInfo{}
+		if g.stack != nil {
+			debugInfo.StackEntries = g.stack.entries()
+		}
+		switch debug := g.debug.(type) {
+		case nil:
+		case string:
+			debugInfo.Detail = debug
+		default:
+			if b, err := json.Marshal(debug); err == nil {
+				debugInfo.Detail = string(b)
+			} else {
+				debugInfo.Detail = fmt.Sprintf("%v", debug)
+			}
+		}
+		details = append(details, debugInfo)
+	}
+
+	if g.localized != nil {
+		details = append(details, &errdetails.LocalizedMessage{
+			Locale:  g.localized.Locale,
+			Message: g.localized.Message,
+		})
+	}
+	return details
+}
+
+// extraStatusDetails return details of st which are not decoded by
+// fromStatusDetails, they are kept in raw form so they can be sent
+// again even when their type is not registered
+func extraStatusDetails(st *status.Status) []*any.Any {
+	var (
+		raw   = st.Proto().GetDetails()
+		extra []*any.Any
+	)
+	for i, detail := range st.Details() {
+		if !isDecodedDetail(detail) {
+			extra = append(extra, raw[i])
+		}
+	}
+	return extra
+}
+
+func isDecodedDetail(detail interface{}) bool {
+	switch d := detail.(type) {
+	case *errdetails.ErrorInfo:
+		// ErrorInfo of MultiError item is decoded by FromGRPCMultiError
+		_, isItem := d.GetMetadata()[errorInfoPathKey]
+		return !isItem
+	case *errdetails.BadRequest, *errdetails.RetryInfo, *errdetails.DebugInfo, *errdetails.LocalizedMessage:
+		return true
+	}
+	return false
+}
+
+func fromStatusDetails(st *status.Status) (*Error, bool) {
+	var (
+		e          = &Error{message: st.Message(), extraDetails: extraStatusDetails(st)}
+		recognized = false
+		errorType  ErrorType
+	)
+	for _, detail := range st.Details() {
+		if !isDecodedDetail(detail) {
+			continue
+		}
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			recognized = true
+			e.reason = d.GetReason()
+			if t, err := strconv.Atoi(d.GetMetadata()[errorInfoTypeKey]); err == nil {
+				errorType = ErrorType(t)
+			}
+			for k, v := range d.GetMetadata() {
+				if strings.HasPrefix(k, errorInfoParamKeyPrefix) {
+					if e.params == nil {
+						e.params = Params{}
+					}
+					e.params[strings.TrimPrefix(k, errorInfoParamKeyPrefix)] = v
+				}
+			}
+			for i := 0; ; i++ {
+				v, ok := d.GetMetadata()[fmt.Sprintf("%s%d", errorInfoDetailKeyPrefix, i)]
+				if !ok {
+					break
+				}
+				e.detail = append(e.detail, v)
+			}
+		case *errdetails.BadRequest:
+			recognized = true
+			for _, v := range d.GetFieldViolations() {
+				e.fieldViolations = append(e.fieldViolations, FieldViolation{
+					Field:       v.GetField(),
+					Description: v.GetDescription(),
+				})
+			}
+		case *errdetails.RetryInfo:
+			recognized = true
+			if d.GetRetryDelay() != nil {
+				e.retryDelay, _ = ptypes.Duration(d.GetRetryDelay())
+			}
+		case *errdetails.DebugInfo:
+			recognized = true
+			var debug interface{}
+			if d.GetDetail() == "" {
+				break
+			} else if err := json.Unmarshal([]byte(d.GetDetail()), &debug); err == nil {
+				e.debug = debug
+			} else {
+				e.debug = d.GetDetail()
+			}
+		case *errdetails.LocalizedMessage:
+			recognized = true
+			e.localized = &LocalizedMessage{
+				Locale:  d.GetLocale(),
+				Message: d.GetMessage(),
+			}
+		}
+	}
+	if !recognized {
+		return nil, false
+	}
+
+	if errorType == 0 {
+		errorType = TypeFromCode(st.Code())
+	}
+	e.code = errorType
+	if st.Code() != CodeFromType(errorType) {
+		e.status = st.Code()
+	}
+	if e.reason == reasonFromCode(st.Code()) {
+		e.reason = ""
+	}
+	return e, true
+}
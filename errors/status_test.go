@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	"github.com/golang/protobuf/ptypes"
+	foundationerrorv2 "github.com/octofoxio/foundation/errors/v2"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestToGRPCErrorDetails(t *testing.T) {
+	err := New(ErrorTypeBadInput, "invalid registration").
+		WithReason("INVALID_REGISTRATION").
+		WithDetail("email").
+		WithFieldViolation("email", "must be an email").
+		WithFieldViolation("age", "must be positive").
+		WithRetryDelay(3*time.Second).
+		WithDebug(map[string]interface{}{"sql": "SELECT 1"}).
+		WithLocalizedMessage("th-TH", "ข้อมูลไม่ถูกต้อง")
+
+	st := ToGRPCError(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "invalid registration", st.Message(), "message must be human readable")
+
+	var found = map[string]bool{}
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			found["info"] = true
+			assert.Equal(t, "INVALID_REGISTRATION", d.Reason)
+			assert.Equal(t, ErrorDomain, d.Domain)
+		case *errdetails.BadRequest:
+			found["badrequest"] = true
+			assert.Len(t, d.FieldViolations, 2)
+		case *errdetails.RetryInfo:
+			found["retry"] = true
+			delay, _ := ptypes.Duration(d.RetryDelay)
+			assert.Equal(t, 3*time.Second, delay)
+		case *errdetails.DebugInfo:
+			found["debug"] = true
+		case *errdetails.LocalizedMessage:
+			found["localized"] = true
+			assert.Equal(t, "th-TH", d.Locale)
+		}
+	}
+	assert.Len(t, found, 5)
+
+	t.Run("round trip", func(t *testing.T) {
+		decoded, ok := FromGRPCError(st.Err())
+		assert.True(t, ok)
+		assert.EqualValues(t, ErrorTypeBadInput, decoded.Type())
+		assert.Equal(t, codes.InvalidArgument, decoded.Code())
+		assert.Equal(t, "INVALID_REGISTRATION", decoded.Reason())
+		assert.Equal(t, err.Error(), decoded.Error())
+		assert.Equal(t, err.GetDetail(), decoded.GetDetail())
+		assert.Equal(t, err.GetFieldViolations(), decoded.GetFieldViolations())
+		assert.Equal(t, err.GetRetryDelay(), decoded.GetRetryDelay())
+		assert.Equal(t, err.GetDebug(), decoded.GetDebug())
+		assert.Equal(t, err.GetLocalizedMessage(), decoded.GetLocalizedMessage())
+	})
+
+	t.Run("round trip through v2", func(t *testing.T) {
+		v2 := foundationerrorv2.FromStatusError(err.ToV2().Status())
+		decoded := FromV2(v2)
+		assert.Equal(t, err.Reason(), decoded.Reason())
+		assert.Equal(t, err.GetFieldViolations(), decoded.GetFieldViolations())
+	})
+
+	t.Run("returned error should be converted by gRPC", func(t *testing.T) {
+		st, ok := status.FromError(err)
+		assert.True(t, ok)
+		assert.Equal(t, codes.InvalidArgument, st.Code())
+		assert.NotEmpty(t, st.Details())
+	})
+}
+
+func TestFromGRPCErrorLegacy(t *testing.T) {
+	legacy := status.Error(codes.Internal, `{"code":403,"message":"forbidden","detail":["admin only"],"debug":"trace"}`)
+	err, ok := FromGRPCError(legacy)
+	assert.True(t, ok)
+	assert.EqualValues(t, ErrorTypeForbidden, err.Type())
+	assert.Equal(t, "forbidden", err.Error())
+	assert.Equal(t, []string{"admin only"}, err.GetDetail())
+	assert.Equal(t, "trace", err.GetDebug())
+
+	_, ok = FromGRPCError(status.Error(codes.Internal, "plain message"))
+	assert.False(t, ok)
+}
+
+func TestReason(t *testing.T) {
+	assert.Equal(t, "NOT_FOUND", New(ErrorTypeNotfound, "").Reason())
+	assert.Equal(t, "INVALID_ARGUMENT", New(ErrorTypeBadInput, "").Reason())
+	assert.Equal(t, "USER_NOT_FOUND", New(ErrorTypeNotfound, "").WithReason("USER_NOT_FOUND").Reason())
+}
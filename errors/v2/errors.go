@@ -6,18 +6,23 @@ package foundationerrorv2
 
 import (
 	"fmt"
+	"github.com/golang/protobuf/proto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"io"
 	"strings"
 )
 
 type Error struct {
 	Type    codes.Code
 	message []string
+	details []proto.Message
+	cause   error
+	stack   stack
 }
 
 func (e *Error) Error() string {
-	return fmt.Sprintf(strings.Join(e.message, "\n"))
+	return strings.Join(e.message, "\n")
 }
 
 func (e *Error) AppendMessage(msg string, args ...interface{}) *Error {
@@ -25,15 +30,116 @@ func (e *Error) AppendMessage(msg string, args ...interface{}) *Error {
 	return e
 }
 
+// WithDetails attach google.rpc.Status details (e.g. errdetails.ErrorInfo)
+// which will be sent along with the status
+func (e *Error) WithDetails(details ...proto.Message) *Error {
+	e.details = append(e.details, details...)
+	return e
+}
+
+func (e *Error) Details() []proto.Message {
+	return e.details
+}
+
+// Status convert error to gRPC status with its details
+func (e *Error) Status() *status.Status {
+	st := status.New(e.Type, e.Error())
+	if len(e.details) == 0 {
+		return st
+	}
+	if withDetails, err := st.WithDetails(e.details...); err == nil {
+		return withDetails
+	}
+	return st
+}
+
+// GRPCStatus allow gRPC server to send *Error
+// returned from handler with its details
+func (e *Error) GRPCStatus() *status.Status {
+	return e.Status()
+}
+
 func FromStatusError(status *status.Status) *Error {
+	var details []proto.Message
+	for _, d := range status.Details() {
+		if m, ok := d.(proto.Message); ok {
+			details = append(details, m)
+		}
+	}
 	return &Error{
 		Type:    status.Code(),
 		message: strings.Split(status.Message(), "\n"),
+		details: details,
 	}
 }
 
+// New create error and capture the caller stack,
+// use NewWithoutStack in hot paths
 func New(Type codes.Code) *Error {
+	return &Error{
+		Type:  Type,
+		stack: callers(1),
+	}
+}
+
+func NewWithoutStack(Type codes.Code) *Error {
 	return &Error{
 		Type: Type,
 	}
 }
+
+// Wrap create error with cause, message of cause
+// is not included in message of the new error
+func Wrap(cause error, Type codes.Code) *Error {
+	if cause == nil {
+		return nil
+	}
+	return &Error{
+		Type:  Type,
+		cause: cause,
+		stack: callers(1),
+	}
+}
+
+func (e *Error) WithCause(cause error) *Error {
+	e.cause = cause
+	return e
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is report whether target has the same code
+func (e *Error) Is(target error) bool {
+	if t, ok := target.(*Error); ok {
+		return e.Type == t.Type
+	}
+	return false
+}
+
+// StackTrace return program counters captured at creation
+func (e *Error) StackTrace() []uintptr {
+	return e.stack
+}
+
+// Format print message with %s and %v,
+// %+v print code, stack and the whole chain of causes
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			_, _ = fmt.Fprintf(s, "%s (code=%s)", e.Error(), e.Type)
+			e.stack.format(s)
+			if e.cause != nil {
+				_, _ = fmt.Fprintf(s, "\ncaused by: %+v", e.cause)
+			}
+			return
+		}
+		fallthrough
+	case 's':
+		_, _ = io.WriteString(s, e.Error())
+	case 'q':
+		_, _ = fmt.Fprintf(s, "%q", e.Error())
+	}
+}
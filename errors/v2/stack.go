@@ -0,0 +1,35 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package foundationerrorv2
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+const maxStackDepth = 32
+
+type stack []uintptr
+
+func callers(skip int) stack {
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+	return pcs[:n]
+}
+
+func (s stack) format(w io.Writer) {
+	if len(s) == 0 {
+		return
+	}
+	frames := runtime.CallersFrames(s)
+	for {
+		frame, more := frames.Next()
+		_, _ = fmt.Fprintf(w, "\n%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+}
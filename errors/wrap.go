@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	"fmt"
+	foundationerrorv2 "github.com/octofoxio/foundation/errors/v2"
+	"io"
+)
+
+// Wrap create error with err as its cause, ErrorType, gRPC code,
+// reason and unknown status details are inherited from err when it is (or wrap) *Error,
+// otherwise the error is internal
+func Wrap(err error, message string) *Error {
+	if err == nil {
+		return nil
+	}
+	return wrap(err, message)
+}
+
+func Wrapf(err error, format string, args ...interface{}) *Error {
+	if err == nil {
+		return nil
+	}
+	return wrap(err, fmt.Sprintf(format, args...))
+}
+
+func wrap(err error, message string) *Error {
+	var e = &Error{
+		code:    ErrorTypeInternal,
+		message: message,
+		cause:   err,
+		stack:   callers(2),
+	}
+	if cause, ok := asError(err); ok {
+		e.code = cause.code
+		e.status = cause.status
+		e.reason = cause.reason
+		e.extraDetails = cause.extraDetails
+	}
+	return e
+}
+
+func (g *Error) Unwrap() error {
+	return g.cause
+}
+
+// Is report whether target has the same ErrorType and gRPC code,
+// so a sentinel error can be matched by errors.Is, when target has
+// a reason (or it is catalog Definition) the reason is matched instead
+//
+// ```
+// var ErrNotFound = errors.NewWithoutStack(errors.ErrorTypeNotfound, "not found")
+// stderrors.Is(errors.Wrap(err, "get user"), ErrNotFound)
+// stderrors.Is(err, ErrUserNotFound) // ErrUserNotFound is *Definition
+// ```
+func (g *Error) Is(target error) bool {
+	switch t := target.(type) {
+	case *Definition:
+		return g.Reason() == t.Reason
+	case *Error:
+		if t.reason != "" {
+			return g.Reason() == t.reason
+		}
+		return g.code == t.code && g.Code() == t.Code()
+	case *foundationerrorv2.Error:
+		return g.Code() == t.Type
+	}
+	return false
+}
+
+// As allow errors.As to convert *Error into *foundationerrorv2.Error
+func (g *Error) As(target interface{}) bool {
+	switch t := target.(type) {
+	case **foundationerrorv2.Error:
+		*t = g.ToV2()
+		return true
+	}
+	return false
+}
+
+// StackTrace return program counters captured at creation
+func (g *Error) StackTrace() []uintptr {
+	if g.stack == nil {
+		return nil
+	}
+	return *g.stack
+}
+
+// Format print message with %s and %v,
+// %+v print the whole chain with stack for logging
+func (g *Error) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			_, _ = fmt.Fprintf(s, "%s (type=%d code=%s reason=%s)", g.message, g.code, g.Code(), g.Reason())
+			if g.stack != nil {
+				g.stack.format(s)
+			}
+			if g.cause != nil {
+				_, _ = fmt.Fprintf(s, "\ncaused by: %+v", g.cause)
+			}
+			return
+		}
+		fallthrough
+	case 's':
+		_, _ = io.WriteString(s, g.message)
+	case 'q':
+		_, _ = fmt.Fprintf(s, "%q", g.message)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	foundationerrorv2 "github.com/octofoxio/foundation/errors/v2"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"testing"
+)
+
+var errUserNotFound = NewWithoutStack(ErrorTypeNotfound, "user not found")
+
+func findUser() error {
+	return New(ErrorTypeNotfound, "user 29 not found")
+}
+
+func TestWrap(t *testing.T) {
+	assert.Nil(t, Wrap(nil, "nothing"))
+
+	err := Wrapf(findUser(), "cannot get profile of %d", 29)
+	assert.Equal(t, "cannot get profile of 29", err.Error())
+	assert.Equal(t, ErrorTypeNotfound, err.Type(), "type should be inherited from cause")
+	assert.True(t, stderrors.Is(err, errUserNotFound))
+	assert.False(t, stderrors.Is(err, New(ErrorTypeInternal, "")))
+	assert.True(t, stderrors.Is(fmt.Errorf("handler: %w", err), errUserNotFound))
+
+	t.Run("generic cause", func(t *testing.T) {
+		cause := fmt.Errorf("connection refused")
+		err := Wrap(cause, "cannot connect to database")
+		assert.Equal(t, ErrorTypeInternal, err.Type())
+		assert.Equal(t, cause, stderrors.Unwrap(err))
+		assert.True(t, stderrors.Is(err, cause))
+	})
+
+	t.Run("As", func(t *testing.T) {
+		var v2 *foundationerrorv2.Error
+		assert.True(t, stderrors.As(err, &v2))
+		assert.Equal(t, codes.NotFound, v2.Type)
+
+		var e *Error
+		assert.True(t, stderrors.As(fmt.Errorf("wrapped: %w", err), &e))
+		assert.Equal(t, err, e)
+	})
+
+	t.Run("v2", func(t *testing.T) {
+		v2 := foundationerrorv2.Wrap(err, codes.Internal).AppendMessage("internal")
+		assert.True(t, stderrors.Is(v2, errUserNotFound))
+		assert.True(t, stderrors.Is(v2, foundationerrorv2.New(codes.Internal)))
+		assert.NotEmpty(t, v2.StackTrace())
+		assert.Contains(t, fmt.Sprintf("%+v", v2), "caused by: cannot get profile of 29")
+		assert.Equal(t, v2.StackTrace(), FromV2(v2).StackTrace())
+	})
+}
+
+func TestStack(t *testing.T) {
+	err := findUser().(*Error)
+	assert.NotEmpty(t, err.StackTrace())
+	assert.Empty(t, errUserNotFound.StackTrace())
+	assert.Contains(t, err.GetDebug().([]string)[0], "errors.findUser")
+
+	wrapped := Wrap(err, "cannot get profile")
+	assert.Equal(t, "cannot get profile", fmt.Sprintf("%v", wrapped))
+	assert.Equal(t, "cannot get profile", fmt.Sprintf("%s", wrapped))
+
+	verbose := fmt.Sprintf("%+v", wrapped)
+	assert.Contains(t, verbose, "cannot get profile (type=404 code=NotFound reason=NOT_FOUND)")
+	assert.Contains(t, verbose, "errors.TestStack")
+	assert.Contains(t, verbose, "caused by: user 29 not found")
+	assert.Contains(t, verbose, "errors.findUser")
+}
@@ -7,6 +7,7 @@ package app
 import (
 	"context"
 	"fmt"
+	"github.com/octofoxio/foundation/errors"
 )
 
 type StringSvc struct{}
@@ -16,6 +17,9 @@ func NewStringSvc() *StringSvc {
 }
 
 func (s *StringSvc) Concat(c context.Context, input *ConcatInput) (*ConcatOutput, error) {
+	if input.Origin == "" {
+		return nil, ErrEmptyInput.New(errors.Params{"field": "origin"})
+	}
 	return &ConcatOutput{
 		Result: fmt.Sprintf("%s%s", input.Origin, input.Extend),
 	}, nil
@@ -0,0 +1,13 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package app
+
+import "github.com/octofoxio/foundation/errors"
+
+var (
+	ErrEmptyInput = errors.Define("STRING_EMPTY_INPUT", errors.ErrorTypeBadInput,
+		"{field} must not be empty",
+		"Returned when a required string parameter is missing")
+)
@@ -0,0 +1,16 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package main
+
+import (
+	"github.com/octofoxio/foundation/errors/catalogcmd"
+	_ "github.com/octofoxio/foundation/examples/stringsvc/app"
+)
+
+// Dump stringsvc error catalog
+// go run ./examples/stringsvc/cmd/errcatalog -format markdown
+func main() {
+	catalogcmd.Main()
+}
@@ -5,8 +5,6 @@
 package main
 
 import (
-	"context"
-	"encoding/json"
 	"github.com/octofoxio/foundation"
 	"github.com/octofoxio/foundation/examples/stringsvc/app"
 	"github.com/octofoxio/foundation/http"
@@ -15,27 +13,6 @@ import (
 	http2 "net/http"
 )
 
-func makeConcatRequestDecoder() http.RequestDecoder {
-	return func(ctx context.Context, r *http2.Request) (i interface{}, e error) {
-		p1 := r.FormValue("param1")
-		p2 := r.FormValue("param2")
-		return &app.ConcatInput{
-			Origin: p1,
-			Extend: p2,
-		}, nil
-	}
-}
-
-func makeConcatResponseEncoder() http.ResponseEncoder {
-	return func(ctx context.Context, response interface{}) (i int, bytes []byte, e error) {
-		var res = response.(*app.ConcatOutput)
-		b, err := json.Marshal(res)
-		if err != nil {
-			return 500, nil, err
-		}
-		return 200, b, nil
-	}
-}
 func main() {
 	var log = logger.New("stringsvc").WithServiceInfo("main")
 	stringsvc := app.NewStringSvc()
@@ -53,16 +30,17 @@ func main() {
 	}()
 
 	httpServer := http.NewServer()
-	httpServer.Get("/concat",
-		http.EndpointHandler(func(ctx context.Context, request interface{}) (i interface{}, e error) {
-			return stringsvc.Concat(ctx, request.(*app.ConcatInput))
-		}),
-		http.RequestDecoderMiddleware(makeConcatRequestDecoder()),
-		http.ResponseEncoderMiddleware(makeConcatResponseEncoder()),
-	)
+	http.HandleFunc(httpServer, http2.MethodGet, "/concat", stringsvc.Concat)
+	if err := http.RegisterGRPCService(httpServer, "app.String", stringsvc, foundation.WithContextServerInterceptor()); err != nil {
+		panic(err)
+	}
+	httpServer.ServeOpenAPI("/openapi.json", http.OpenAPIInfo{Title: "stringsvc", Version: "1.0.0"})
+	httpServer.ServeSwaggerUI("/docs", nil, "", "/openapi.json")
 
 	log.Info("HTTP Stringsvc start at :3009")
-	log.Println("Try it on http://localhost:3009/concat?param1=hello&param2=world")
+	log.Println("Try it on http://localhost:3009/concat?origin=hello&extend=world")
+	log.Println(`or curl -d '{"origin":"hello","extend":"world"}' http://localhost:3009/app.String/Concat`)
+	log.Println("API docs on http://localhost:3009/docs/")
 	log.Info("GRPC Stringsvc start at :3010")
 	log.Println("Try it on ./client")
 	err := http2.ListenAndServe("0.0.0.0:3009", httpServer)
@@ -13,10 +13,25 @@ import (
 	"time"
 )
 
+// NewGRPCServer create server with error sanitizer (see SetDebugPolicy)
+// and panic recovery as the first interceptors, debug decision is
+// recorded after the last one so claims from interceptors are used.
+// Use NewGRPCServerWithStream to add stream interceptors
 func NewGRPCServer(interceptors ...grpc.UnaryServerInterceptor) *grpc.Server {
-	interceptors = append(interceptors) // panic interceptor must be implemented outside foundation
+	return NewGRPCServerWithStream(interceptors, nil)
+}
 
-	interceptors = append([]grpc.UnaryServerInterceptor{}, interceptors...)
+func NewGRPCServerWithStream(interceptors []grpc.UnaryServerInterceptor, streamInterceptors []grpc.StreamServerInterceptor) *grpc.Server {
+	interceptors = append([]grpc.UnaryServerInterceptor{
+		SanitizeErrorServerInterceptor(),
+		PanicRecoveryInterceptor(),
+	}, interceptors...)
+	interceptors = append(interceptors, DebugDecisionServerInterceptor())
+	streamInterceptors = append([]grpc.StreamServerInterceptor{
+		SanitizeErrorStreamServerInterceptor(),
+		PanicRecoveryStreamInterceptor(),
+	}, streamInterceptors...)
+	streamInterceptors = append(streamInterceptors, DebugDecisionStreamServerInterceptor())
 	var grpcServerOptions = []grpc.ServerOption{
 		// To keep connection alive in-case
 		// when GRPC is working
@@ -27,6 +42,7 @@ func NewGRPCServer(interceptors ...grpc.UnaryServerInterceptor) *grpc.Server {
 			},
 		),
 		grpc_middleware.WithUnaryServerChain(interceptors...),
+		grpc_middleware.WithStreamServerChain(streamInterceptors...),
 	}
 
 	// Use TLS certification if provide to ENV
@@ -6,6 +6,7 @@ package foundation
 
 import (
 	"context"
+	"github.com/octofoxio/foundation/errors"
 	"github.com/octofoxio/foundation/logger"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -30,6 +31,18 @@ func WithAuthorizationDialOption(accessToken string) grpc.DialOption {
 	})
 }
 
+// ErrorDecoderClientInterceptor convert status error from server
+// into *errors.Error, so caller can check Type() or Code() directly
+func ErrorDecoderClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil {
+			return nil
+		}
+		return errors.From(err)
+	}
+}
+
 func MakeDial(endpoint string, dialOptions ...grpc.DialOption) (*grpc.ClientConn, error) {
 	var log = logger.New("grpc").WithServiceID("foundation").WithServiceInfo("grpc")
 	options := []grpc.DialOption{
@@ -38,6 +51,7 @@ func MakeDial(endpoint string, dialOptions ...grpc.DialOption) (*grpc.ClientConn
 				Time:                50 * time.Second,
 				PermitWithoutStream: true,
 			}),
+		grpc.WithChainUnaryInterceptor(ErrorDecoderClientInterceptor()),
 	}
 	for _, o := range dialOptions {
 		options = append(options, o)
@@ -7,36 +7,12 @@ package foundation
 import (
 	"context"
 	"fmt"
-	foundationerrorv2 "github.com/octofoxio/foundation/errors/v2"
+	"github.com/octofoxio/foundation/errors"
 	"github.com/octofoxio/foundation/logger"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
 )
 
-func PanicRecoveryInterceptor() grpc.UnaryServerInterceptor {
-	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-		defer func() {
-			if r := recover(); r != nil {
-				switch e := r.(type) {
-				case *foundationerrorv2.Error:
-					err = status.Error(e.Type, e.Error())
-				case error:
-					err = status.Error(codes.Unknown, err.Error())
-				case string:
-					err = status.Error(codes.Unknown, e)
-				default:
-					fmt.Println("======== Unknown error occurs =========")
-					fmt.Println(r)
-					panic(r)
-				}
-			}
-		}()
-		return handler(ctx, req)
-	}
-}
-
 // logging a method calling information
 func WithMethodCallingLoggerServerInterceptor(logger *logger.Logger) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
@@ -76,9 +52,28 @@ func WithContextServerInterceptor() grpc.UnaryServerInterceptor {
 			if len(requestIDs) > 0 {
 				ctx = context.WithValue(ctx, FoundationRequestIDContextKey, requestIDs[0])
 			}
+
+			locales := md.Get(GRPC_METADATA_ACCEPT_LANGUAGE_KEY)
+			if len(locales) > 0 && locales[0] != "" {
+				ctx = AppendLocaleToContext(ctx, locales[0])
+				ctx = metadata.AppendToOutgoingContext(ctx, GRPC_METADATA_ACCEPT_LANGUAGE_KEY, locales[0])
+			}
 		}
 		ctx = NewContext(ctx)
 		ctx = AppendRequestIDToContext(ctx, GetRequestIDFromContext(ctx))
 		return handler(ctx, req)
 	}
 }
+
+// LocalizeErrorServerInterceptor render message of returned error
+// in client locale (from Accept-Language metadata) as LocalizedMessage detail,
+// it must be placed after WithContextServerInterceptor
+func LocalizeErrorServerInterceptor(localizer *errors.Localizer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		resp, err = handler(ctx, req)
+		if err != nil {
+			err = localizer.Localize(err, GetLocaleFromContext(ctx))
+		}
+		return resp, err
+	}
+}
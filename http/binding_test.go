@@ -0,0 +1,48 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBindValues(t *testing.T) {
+	type embedded struct {
+		Page int `form:"page"`
+	}
+	type input struct {
+		embedded
+		Name     string        `json:"name,omitempty"`
+		Score    float64       `form:"score"`
+		IDs      []uint        `form:"id"`
+		Timeout  time.Duration `form:"timeout"`
+		Since    time.Time     `form:"since"`
+		Ignored  string        `json:"-"`
+		internal string
+	}
+	var v input
+	err := bindValues(&v, "form", map[string][]string{
+		"page":    {"2"},
+		"name":    {"john"},
+		"score":   {"1.5"},
+		"id":      {"1", "2"},
+		"timeout": {"3s"},
+		"since":   {"2019-01-02T00:00:00Z"},
+	}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v.Page)
+	assert.Equal(t, "john", v.Name)
+	assert.Equal(t, 1.5, v.Score)
+	assert.Equal(t, []uint{1, 2}, v.IDs)
+	assert.Equal(t, 3*time.Second, v.Timeout)
+	assert.Equal(t, 2019, v.Since.Year())
+
+	assert.Error(t, bindValues(&v, "form", map[string][]string{"Ignored": {"x"}}, true))
+	assert.Error(t, bindValues(&v, "form", map[string][]string{"page": {"x"}}, false))
+	assert.NoError(t, bindValues(&v, "form", map[string][]string{"unknown": {"x"}}, false))
+	assert.Error(t, bindValues(v, "form", nil, false), "target must be pointer")
+}
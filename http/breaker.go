@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"github.com/octofoxio/foundation/errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.Define("CIRCUIT_OPEN", errors.ErrorTypeUnavailable, "circuit of {name} is open", "Upstream service fail continuously, requests are rejected until it is tried again")
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// CircuitBreaker reject calls after threshold consecutive failures,
+// once openTimeout passed a single trial call is allowed and
+// the circuit is closed again when it succeed
+type CircuitBreaker struct {
+	name        string
+	threshold   int
+	openTimeout time.Duration
+	mux         *sync.Mutex
+	state       circuitState
+	failures    int
+	openedAt    time.Time
+	now         func() time.Time
+}
+
+func NewCircuitBreaker(name string, threshold int, openTimeout time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &CircuitBreaker{
+		name:        name,
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		mux:         &sync.Mutex{},
+		now:         time.Now,
+	}
+}
+
+// Allow return ErrCircuitOpen when call must not be made,
+// the error has retry delay of the remaining open time
+func (b *CircuitBreaker) Allow() error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	switch b.state {
+	case circuitOpen:
+		remaining := b.openTimeout - b.now().Sub(b.openedAt)
+		if remaining > 0 {
+			return ErrCircuitOpen.New(errors.Params{"name": b.name}).WithRetryDelay(remaining)
+		}
+		b.state = circuitHalfOpen
+		return nil
+	case circuitHalfOpen:
+		// trial call is in flight
+		return ErrCircuitOpen.New(errors.Params{"name": b.name}).WithRetryDelay(b.openTimeout)
+	}
+	return nil
+}
+
+func (b *CircuitBreaker) Success() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.state = circuitClosed
+	b.failures = 0
+}
+
+func (b *CircuitBreaker) Failure() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		b.state = circuitOpen
+		b.openedAt = b.now()
+	}
+}
+
+// abort give up trial call which did not complete (e.g. canceled),
+// so the next call can be the trial
+func (b *CircuitBreaker) abort() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.state == circuitHalfOpen {
+		b.state = circuitOpen
+	}
+}
+
+// IsOpen report whether calls are rejected, it is true
+// while the trial call of half-open circuit is in flight
+func (b *CircuitBreaker) IsOpen() bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.state != circuitClosed
+}
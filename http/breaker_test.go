@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"github.com/octofoxio/foundation/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var now = time.Now()
+	var b = NewCircuitBreaker("payment", 2, 10*time.Second)
+	b.now = func() time.Time { return now }
+
+	assert.NoError(t, b.Allow())
+	b.Failure()
+	b.Success()
+	b.Failure()
+	assert.False(t, b.IsOpen(), "failures must be consecutive")
+	b.Failure()
+	assert.True(t, b.IsOpen())
+
+	err := b.Allow()
+	assert.Equal(t, ErrCircuitOpen.Reason, errors.From(err).Reason())
+	assert.Equal(t, 10*time.Second, errors.From(err).GetRetryDelay())
+
+	// single trial after open timeout
+	now = now.Add(10 * time.Second)
+	assert.NoError(t, b.Allow())
+	assert.Error(t, b.Allow())
+	b.Failure()
+	assert.Error(t, b.Allow(), "failed trial open the circuit again")
+
+	now = now.Add(10 * time.Second)
+	assert.NoError(t, b.Allow())
+	b.abort()
+	assert.NoError(t, b.Allow(), "aborted trial can be tried again")
+	b.Success()
+	assert.False(t, b.IsOpen())
+	assert.NoError(t, b.Allow())
+}
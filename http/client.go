@@ -0,0 +1,463 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/golang/protobuf/proto"
+	"github.com/octofoxio/foundation"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/octofoxio/foundation/logger"
+	"io"
+	"io/ioutil"
+	"math"
+	mathrand "math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	HeaderTraceStateKey     = "tracestate"
+	HeaderIdempotencyKey    = "Idempotency-Key"
+	HeaderRetryAfterKey     = "Retry-After"
+	DefaultClientTimeout    = 30 * time.Second
+	maxErrorResponseSize    = 1 << 20
+	maxErrorBodyInMessage   = 256
+	defaultClientLoggerName = "http-client"
+)
+
+var ErrUpstreamUnavailable = errors.Define("UPSTREAM_UNAVAILABLE", errors.ErrorTypeUnavailable, "request to {host} failed: {error}", "Upstream service cannot be reached")
+
+// RetryPolicy is exponential backoff with full jitter, MaxAttempts
+// include the first attempt. Only idempotent requests (GET, HEAD, OPTIONS,
+// PUT, DELETE or request with Idempotency-Key header) are retried,
+// Retry-After of the response is used when it is not longer than MaxBackoff
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// RetryOn decide whether the attempt should be retried,
+	// default retry network errors, 429, 502, 503 and 504
+	RetryOn func(resp *http.Response, err error) bool
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Multiplier:     2,
+}
+
+func defaultRetryOn(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff return delay before the next attempt, attempt start from 1
+func (p RetryPolicy) backoff(attempt int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if delay, ok := retryAfter(resp); ok {
+			return delay, delay <= p.MaxBackoff
+		}
+	}
+	var max = float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	if max > float64(p.MaxBackoff) {
+		max = float64(p.MaxBackoff)
+	}
+	return time.Duration(mathrand.Int63n(int64(max) + 1)), true
+}
+
+// Client call other HTTP services with the foundation context, RequestID
+// and W3C traceparent are sent from ctx, error responses are decoded into *errors.Error.
+// Access token of the incoming request is sent only by WithAuthorization
+//
+// ```
+// var payment = http.NewClient("https://api.payment.com/v1",
+// http.WithRetry(http.DefaultRetryPolicy),
+// http.WithCircuitBreaker(http.NewCircuitBreaker("payment", 5, 30*time.Second)),
+// )
+// var users = http.NewClient("http://users.internal", http.WithAuthorization())
+// var charge ChargeOutput
+// err := payment.PostJSON(ctx, "/charges", &ChargeInput{Amount: 100}, &charge)
+// ```
+type Client struct {
+	baseURL       string
+	httpClient    *http.Client
+	header        http.Header
+	retry         *RetryPolicy
+	breaker       *CircuitBreaker
+	authorization bool
+	log           *logger.Logger
+}
+
+type ClientOption func(c *Client)
+
+// WithHTTPClient change underlying client, default has DefaultClientTimeout
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithHeader add header to every requests (e.g. API key)
+func WithHeader(key, value string) ClientOption {
+	return func(c *Client) {
+		c.header.Add(key, value)
+	}
+}
+
+func WithRetry(policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		if policy.RetryOn == nil {
+			policy.RetryOn = defaultRetryOn
+		}
+		if policy.Multiplier < 1 {
+			policy.Multiplier = 1
+		}
+		c.retry = &policy
+	}
+}
+
+// WithCircuitBreaker count network errors and 5xx responses as
+// failure, the breaker can be shared by clients of the same service
+func WithCircuitBreaker(breaker *CircuitBreaker) ClientOption {
+	return func(c *Client) {
+		c.breaker = breaker
+	}
+}
+
+// WithAuthorization send access token of the incoming request as
+// Authorization header, use it only for trusted (internal) services
+func WithAuthorization() ClientOption {
+	return func(c *Client) {
+		c.authorization = true
+	}
+}
+
+func NewClient(baseURL string, options ...ClientOption) *Client {
+	var c = &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: DefaultClientTimeout},
+		header:     http.Header{},
+		log:        logger.New(defaultClientLoggerName),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// NewRequest create request to path of base URL, body is sent
+// as JSON (jsonpb for proto messages) when it is not nil
+func (c *Client) NewRequest(ctx context.Context, method string, path string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := encodeJSONPB(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set(HeaderContentTypeKey, ContentTypeJSON)
+	}
+	req.Header.Set(HeaderAcceptKey, ContentTypeJSON)
+	return req, nil
+}
+
+// Do send request with headers from ctx, retry and circuit breaker.
+// Response with status 4xx or 5xx is returned as *errors.Error
+// and its body is closed
+func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	req = req.Clone(ctx)
+	c.applyHeaders(ctx, req)
+	var log = c.logger(ctx).WithField("host", req.URL.Host)
+
+	var attempts = 1
+	if c.retry != nil && c.retry.MaxAttempts > 1 && isIdempotent(req) {
+		attempts = c.retry.MaxAttempts
+	}
+	for attempt := 1; ; attempt++ {
+		if c.breaker != nil {
+			if err := c.breaker.Allow(); err != nil {
+				return nil, err
+			}
+		}
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		var start = time.Now()
+		resp, err := c.httpClient.Do(req)
+		c.report(ctx, resp, err)
+		if ctx.Err() != nil {
+			closeBody(resp)
+			return nil, ctx.Err()
+		}
+		entry := log.WithField("method", req.Method).
+			WithField("path", req.URL.Path).
+			WithField("attempt", attempt).
+			WithField("duration", time.Since(start).Round(time.Microsecond).String())
+		if err != nil {
+			entry = entry.WithError(err)
+		} else {
+			entry = entry.WithField("status", resp.StatusCode)
+		}
+
+		if attempt < attempts && c.retry.RetryOn(resp, err) {
+			if delay, ok := c.retry.backoff(attempt, resp); ok {
+				entry.Warn("http client retry")
+				closeBody(resp)
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(delay):
+				}
+				continue
+			}
+		}
+		if err != nil {
+			entry.Warn("http client")
+			return nil, ErrUpstreamUnavailable.Wrap(err, errors.Params{"host": req.URL.Host, "error": err.Error()})
+		}
+		if resp.StatusCode >= http.StatusBadRequest {
+			entry.Warn("http client")
+			return nil, decodeErrorResponse(resp)
+		}
+		entry.Info("http client")
+		return resp, nil
+	}
+}
+
+// DoJSON send input as JSON and decode response into output,
+// input and output can be nil
+func (c *Client) DoJSON(ctx context.Context, method string, path string, input interface{}, output interface{}) error {
+	req, err := c.NewRequest(ctx, method, path, input)
+	if err != nil {
+		return err
+	}
+	resp, err := c.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp)
+	if output == nil {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return ErrUpstreamUnavailable.Wrap(err, errors.Params{"host": req.URL.Host, "error": err.Error()})
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	if _, ok := output.(proto.Message); ok {
+		err = decodeJSONPB(b, output, newCodecConfig(nil))
+	} else {
+		err = json.Unmarshal(b, output)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "invalid response from %s", req.URL.Host)
+	}
+	return nil
+}
+
+func (c *Client) GetJSON(ctx context.Context, path string, output interface{}) error {
+	return c.DoJSON(ctx, http.MethodGet, path, nil, output)
+}
+
+func (c *Client) PostJSON(ctx context.Context, path string, input interface{}, output interface{}) error {
+	return c.DoJSON(ctx, http.MethodPost, path, input, output)
+}
+
+func (c *Client) PutJSON(ctx context.Context, path string, input interface{}, output interface{}) error {
+	return c.DoJSON(ctx, http.MethodPut, path, input, output)
+}
+
+func (c *Client) DeleteJSON(ctx context.Context, path string, output interface{}) error {
+	return c.DoJSON(ctx, http.MethodDelete, path, nil, output)
+}
+
+func (c *Client) applyHeaders(ctx context.Context, req *http.Request) {
+	for key, values := range c.header {
+		if req.Header.Get(key) == "" {
+			req.Header[key] = values
+		}
+	}
+	var requestID = foundation.GetRequestIDFromContext(ctx)
+	if requestID != "" && req.Header.Get(HeaderRequestIDKey) == "" {
+		req.Header.Set(HeaderRequestIDKey, requestID)
+	}
+	if token := foundation.GetAccessTokenFromContext(ctx); c.authorization && token != "" && req.Header.Get(HeaderAuthorizationKey) == "" {
+		if !strings.Contains(token, " ") {
+			token = "Bearer " + token
+		}
+		req.Header.Set(HeaderAuthorizationKey, token)
+	}
+	if req.Header.Get(HeaderTraceParentKey) == "" {
+		if traceParent, traceState := outgoingTraceParent(ctx, requestID); traceParent != "" {
+			req.Header.Set(HeaderTraceParentKey, traceParent)
+			if traceState != "" {
+				req.Header.Set(HeaderTraceStateKey, traceState)
+			}
+		}
+	}
+}
+
+// outgoingTraceParent continue trace of the incoming request with new parent ID,
+// request ID which is valid trace ID (e.g. it is from traceparent) start new trace
+func outgoingTraceParent(ctx context.Context, requestID string) (traceParent string, traceState string) {
+	var traceID, flags = "", "01"
+	if r, ok := ctx.Value(RequestContextKey).(*http.Request); ok {
+		if incoming := r.Header.Get(HeaderTraceParentKey); traceIDFromTraceParent(incoming) != "" {
+			parts := strings.Split(strings.TrimSpace(incoming), "-")
+			traceID, flags, traceState = parts[1], parts[3], r.Header.Get(HeaderTraceStateKey)
+		}
+	}
+	if traceID == "" && len(requestID) == 32 && isHex(requestID) && strings.Trim(requestID, "0") != "" {
+		traceID = requestID
+	}
+	if traceID == "" {
+		return "", ""
+	}
+	var parentID = make([]byte, 8)
+	if _, err := rand.Read(parentID); err != nil {
+		return "", ""
+	}
+	return "00-" + traceID + "-" + hex.EncodeToString(parentID) + "-" + flags, traceState
+}
+
+func (c *Client) report(ctx context.Context, resp *http.Response, err error) {
+	if c.breaker == nil {
+		return
+	}
+	switch {
+	case ctx.Err() != nil:
+		c.breaker.abort()
+	case err != nil || resp.StatusCode >= http.StatusInternalServerError:
+		c.breaker.Failure()
+	default:
+		c.breaker.Success()
+	}
+}
+
+// logger return logger of ctx without warning when ctx does not have one
+func (c *Client) logger(ctx context.Context) *logger.Logger {
+	if log, ok := ctx.Value(foundation.FoundationLoggerContextKey).(*logger.Logger); ok && log != nil {
+		return log
+	}
+	return c.log
+}
+
+func isIdempotent(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		// body cannot be sent again
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(HeaderIdempotencyKey) != ""
+}
+
+func closeBody(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+}
+
+// decodeErrorResponse convert ErrorResponse of foundation service
+// into *errors.Error, other bodies become message of error
+// with ErrorType of the status code
+func decodeErrorResponse(resp *http.Response) *errors.Error {
+	defer closeBody(resp)
+	b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorResponseSize))
+	var t = errors.TypeFromHTTPStatus(resp.StatusCode)
+
+	var body ErrorResponse
+	if err := json.Unmarshal(b, &body); err == nil && body.Message != "" {
+		var e = errors.NewWithoutStack(t, body.Message)
+		if body.Reason != "" {
+			e = e.WithReason(body.Reason)
+		}
+		for _, detail := range body.Details {
+			e = e.WithDetail(detail)
+		}
+		for _, v := range body.FieldViolations {
+			e = e.WithFieldViolation(v.Field, v.Description)
+		}
+		if body.LocalizedMessage != nil {
+			e = e.WithLocalizedMessage(body.LocalizedMessage.Locale, body.LocalizedMessage.Message)
+		}
+		return withRetryAfter(e, resp)
+	}
+
+	var message = http.StatusText(resp.StatusCode)
+	if text := strings.TrimSpace(string(b)); text != "" {
+		if len(text) > maxErrorBodyInMessage {
+			// do not cut multi-byte character in half
+			var n = maxErrorBodyInMessage
+			for n > 0 && !utf8.RuneStart(text[n]) {
+				n--
+			}
+			text = text[:n]
+		}
+		message += ": " + text
+	}
+	return withRetryAfter(errors.NewWithoutStack(t, message), resp)
+}
+
+func withRetryAfter(e *errors.Error, resp *http.Response) *errors.Error {
+	if delay, ok := retryAfter(resp); ok && delay > 0 {
+		return e.WithRetryDelay(delay)
+	}
+	return e
+}
+
+// retryAfter parse Retry-After in seconds or HTTP-date,
+// date in the past is zero delay
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	var value = resp.Header.Get(HeaderRetryAfterKey)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
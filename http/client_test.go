@@ -0,0 +1,166 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	stderrors "errors"
+	"github.com/octofoxio/foundation"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+var errGreetingNotAllowed = errors.Define("GREETING_NOT_ALLOWED", errors.ErrorTypeForbidden, "cannot greet {name}", "Test error of client")
+
+func TestClient_Propagation(t *testing.T) {
+	var upstream = NewServer()
+	var header http.Header
+	HandleFunc(upstream, http.MethodPost, "/greet", func(ctx context.Context, input *greetInput) (*greetOutput, error) {
+		header = getRequestFromContext(ctx).Header
+		if input.Name == "root" {
+			return nil, errGreetingNotAllowed.New(errors.Params{"name": input.Name}).WithFieldViolation("name", "reserved")
+		}
+		return &greetOutput{Message: "hello " + input.Name}, nil
+	})
+	upstreamServer := httptest.NewServer(upstream)
+	defer upstreamServer.Close()
+
+	var client = NewClient(upstreamServer.URL+"/", WithAuthorization())
+	var s = NewServer()
+	HandleFunc(s, http.MethodPost, "/greet", func(ctx context.Context, input *greetInput) (*greetOutput, error) {
+		var output greetOutput
+		err := client.PostJSON(ctx, "/greet", input, &output)
+		return &output, err
+	})
+
+	var traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	r := httptest.NewRequest(http.MethodPost, "/greet", strings.NewReader(`{"name": "john"}`))
+	r.Header.Set(HeaderRequestIDKey, "req-1")
+	r.Header.Set(HeaderAuthorizationKey, "Bearer token")
+	r.Header.Set(HeaderTraceParentKey, traceParent)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message": "hello john"}`, w.Body.String())
+	assert.Equal(t, "req-1", header.Get(HeaderRequestIDKey))
+	assert.Equal(t, "Bearer token", header.Get(HeaderAuthorizationKey))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceIDFromTraceParent(header.Get(HeaderTraceParentKey)))
+	assert.NotEqual(t, traceParent, header.Get(HeaderTraceParentKey), "parent ID is the client span")
+
+	// error response is decoded and the reason is kept
+	// access token is not sent without WithAuthorization
+	ctx := context.WithValue(foundation.NewContext(context.Background()), foundation.FoundationAccessTokenContextKey, "token")
+	err := NewClient(upstreamServer.URL).PostJSON(ctx, "/greet", &greetInput{Name: "root"}, nil)
+	assert.True(t, stderrors.Is(err, errGreetingNotAllowed))
+	e := errors.From(err)
+	assert.Equal(t, errors.ErrorTypeForbidden, e.Type())
+	assert.Equal(t, "cannot greet root", e.Error())
+	assert.Equal(t, []errors.FieldViolation{{Field: "name", Description: "reserved"}}, e.GetFieldViolations())
+	assert.Empty(t, header.Get(HeaderAuthorizationKey))
+}
+
+func TestClient_Retry(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1)%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("try again"))
+			return
+		}
+		_, _ = w.Write([]byte(`{"message": "ok"}`))
+	}))
+	defer server.Close()
+	var client = NewClient(server.URL, WithRetry(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}))
+	var ctx = context.Background()
+
+	var output greetOutput
+	assert.NoError(t, client.GetJSON(ctx, "/", &output))
+	assert.Equal(t, "ok", output.Message)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&calls))
+
+	// POST is not idempotent
+	err := client.PostJSON(ctx, "/", &greetInput{Name: "john"}, &output)
+	assert.Equal(t, errors.ErrorTypeUnavailable, errors.From(err).Type())
+	assert.Equal(t, "Service Unavailable: try again", err.Error())
+	assert.EqualValues(t, 4, atomic.LoadInt32(&calls))
+
+	// unless it has idempotency key, the body is sent again
+	req, err := client.NewRequest(ctx, http.MethodPost, "/", &greetInput{Name: "john"})
+	assert.NoError(t, err)
+	req.Header.Set(HeaderIdempotencyKey, "key-1")
+	resp, err := client.Do(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_ = resp.Body.Close()
+	assert.EqualValues(t, 6, atomic.LoadInt32(&calls))
+}
+
+func TestRetryAfter(t *testing.T) {
+	var policy = RetryPolicy{InitialBackoff: time.Millisecond, MaxBackoff: time.Minute, Multiplier: 2}
+	var response = func(retryAfter string) *http.Response {
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{HeaderRetryAfterKey: []string{retryAfter}}, Body: http.NoBody}
+	}
+
+	delay, ok := policy.backoff(1, response("3"))
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, delay)
+
+	delay, ok = policy.backoff(1, response(time.Now().Add(30*time.Second).UTC().Format(http.TimeFormat)))
+	assert.True(t, ok)
+	assert.True(t, delay > 25*time.Second && delay <= 30*time.Second, delay.String())
+	assert.True(t, decodeErrorResponse(response(time.Now().Add(30*time.Second).UTC().Format(http.TimeFormat))).GetRetryDelay() > 25*time.Second)
+
+	_, ok = policy.backoff(1, response(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)))
+	assert.False(t, ok, "longer than MaxBackoff")
+
+	delay, ok = policy.backoff(1, response(time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)))
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), delay)
+
+	delay, ok = policy.backoff(1, response("soon"))
+	assert.True(t, ok)
+	assert.True(t, delay <= time.Millisecond, "fallback to jittered backoff")
+}
+
+func TestDecodeErrorResponse_Truncate(t *testing.T) {
+	var body = strings.Repeat("a", maxErrorBodyInMessage-1) + "ก" + "tail"
+	e := decodeErrorResponse(&http.Response{StatusCode: http.StatusBadGateway, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(body))})
+	assert.True(t, utf8.ValidString(e.Error()))
+	assert.Equal(t, "Bad Gateway: "+strings.Repeat("a", maxErrorBodyInMessage-1), e.Error())
+}
+
+func TestClient_CircuitBreaker(t *testing.T) {
+	var calls int32
+	var healthy int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	var breaker = NewCircuitBreaker("upstream", 2, time.Minute)
+	var client = NewClient(server.URL, WithCircuitBreaker(breaker))
+	var ctx = context.Background()
+
+	assert.Equal(t, errors.ErrorTypeInternal, errors.From(client.GetJSON(ctx, "/", nil)).Type())
+	assert.Equal(t, errors.ErrorTypeInternal, errors.From(client.GetJSON(ctx, "/", nil)).Type())
+	err := client.GetJSON(ctx, "/", nil)
+	assert.True(t, stderrors.Is(err, ErrCircuitOpen))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls), "request is not sent while circuit is open")
+
+	breaker.now = func() time.Time { return time.Now().Add(time.Minute) }
+	atomic.StoreInt32(&healthy, 1)
+	assert.NoError(t, client.GetJSON(ctx, "/", nil))
+	assert.False(t, breaker.IsOpen())
+}
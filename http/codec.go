@@ -0,0 +1,355 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/mux"
+	"github.com/octofoxio/foundation/errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+const (
+	ContentTypeJSON      = "application/json"
+	ContentTypeProtobuf  = "application/x-protobuf"
+	ContentTypeForm      = "application/x-www-form-urlencoded"
+	ContentTypeMultipart = "multipart/form-data"
+
+	HeaderContentTypeKey = "Content-Type"
+	HeaderAcceptKey      = "Accept"
+
+	// DefaultBodyLimit is maximum size of request body
+	// which decoders read, use WithBodyLimit to change it
+	DefaultBodyLimit int64 = 4 << 20
+)
+
+var (
+	ErrInvalidRequestBody   = errors.Define("INVALID_REQUEST_BODY", errors.ErrorTypeBadInput, "invalid request body: {error}", "Request body cannot be decoded")
+	ErrInvalidRequestParam  = errors.Define("INVALID_REQUEST_PARAM", errors.ErrorTypeBadInput, "invalid request parameter: {error}", "Path, query or form parameter cannot be decoded")
+	ErrRequestTooLarge      = errors.Define("REQUEST_TOO_LARGE", http.StatusRequestEntityTooLarge, "request body is larger than {limit} bytes", "Request body exceed limit of the route")
+	ErrUnsupportedMediaType = errors.Define("UNSUPPORTED_MEDIA_TYPE", http.StatusUnsupportedMediaType, "content type {contentType} is not supported", "Request body is not in format which the route accept")
+)
+
+type codecConfig struct {
+	bodyLimit  int64
+	strict     bool
+	statusCode int
+}
+
+type CodecOption func(c *codecConfig)
+
+// WithBodyLimit change maximum size of request body
+func WithBodyLimit(limit int64) CodecOption {
+	return func(c *codecConfig) {
+		c.bodyLimit = limit
+	}
+}
+
+// WithStrict reject request which contain unknown fields
+func WithStrict() CodecOption {
+	return func(c *codecConfig) {
+		c.strict = true
+	}
+}
+
+// WithStatusCode change status code of success response, default is 200
+func WithStatusCode(code int) CodecOption {
+	return func(c *codecConfig) {
+		c.statusCode = code
+	}
+}
+
+func newCodecConfig(options []CodecOption) *codecConfig {
+	var c = &codecConfig{
+		bodyLimit:  DefaultBodyLimit,
+		statusCode: http.StatusOK,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// newInput create new instance of the prototype type,
+// prototype must be pointer (e.g. &CreateUserInput{})
+func newInput(prototype interface{}) interface{} {
+	return reflect.New(reflect.TypeOf(prototype).Elem()).Interface()
+}
+
+func mustBePointer(prototype interface{}) {
+	if t := reflect.TypeOf(prototype); t == nil || t.Kind() != reflect.Ptr {
+		panic(fmt.Sprintf("decoder prototype must be pointer, got %T", prototype))
+	}
+}
+
+func readBody(r *http.Request, c *codecConfig) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, c.bodyLimit+1))
+	if err != nil {
+		return nil, bodyReadError(err)
+	}
+	if int64(len(b)) > c.bodyLimit {
+		return nil, ErrRequestTooLarge.New(errors.Params{"limit": c.bodyLimit})
+	}
+	return b, nil
+}
+
+// bindPathVars set fields from gorilla/mux path variables,
+// fields are matched by `path` tag or json name
+func bindPathVars(r *http.Request, input interface{}) error {
+	var vars = mux.Vars(r)
+	if len(vars) == 0 {
+		return nil
+	}
+	var values = make(map[string][]string, len(vars))
+	for k, v := range vars {
+		values[k] = []string{v}
+	}
+	if err := bindValues(input, "path", values, false); err != nil {
+		return ErrInvalidRequestParam.Wrap(err, errors.Params{"error": err.Error()})
+	}
+	return nil
+}
+
+type bodyDecoder func(body []byte, input interface{}, c *codecConfig) error
+
+func decodeJSON(body []byte, input interface{}, c *codecConfig) error {
+	var decoder = json.NewDecoder(bytes.NewReader(body))
+	if c.strict {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder.Decode(input)
+}
+
+func decodeJSONPB(body []byte, input interface{}, c *codecConfig) error {
+	var unmarshaler = jsonpb.Unmarshaler{AllowUnknownFields: !c.strict}
+	return unmarshaler.Unmarshal(bytes.NewReader(body), input.(proto.Message))
+}
+
+func decodeProtobuf(body []byte, input interface{}, c *codecConfig) error {
+	return proto.Unmarshal(body, input.(proto.Message))
+}
+
+func makeBodyDecoder(prototype interface{}, decode bodyDecoder, options []CodecOption) RequestDecoder {
+	mustBePointer(prototype)
+	var c = newCodecConfig(options)
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		var input = newInput(prototype)
+		body, err := readBody(r, c)
+		if err != nil {
+			return nil, err
+		}
+		if len(body) > 0 {
+			if err := decode(body, input, c); err != nil {
+				return nil, ErrInvalidRequestBody.Wrap(err, errors.Params{"error": err.Error()})
+			}
+		}
+		if err := bindPathVars(r, input); err != nil {
+			return nil, err
+		}
+		return input, nil
+	}
+}
+
+// JSONRequestDecoder decode JSON body into new instance of prototype
+//
+// ```
+// s.Post("/users/{id}", handler, http.RequestDecoderMiddleware(http.JSONRequestDecoder(&UpdateUserInput{})))
+// ```
+func JSONRequestDecoder(prototype interface{}, options ...CodecOption) RequestDecoder {
+	return makeBodyDecoder(prototype, decodeJSON, options)
+}
+
+// JSONPBRequestDecoder decode protobuf JSON mapping (jsonpb) body
+func JSONPBRequestDecoder(prototype proto.Message, options ...CodecOption) RequestDecoder {
+	return makeBodyDecoder(prototype, decodeJSONPB, options)
+}
+
+// ProtobufRequestDecoder decode binary protobuf body
+func ProtobufRequestDecoder(prototype proto.Message, options ...CodecOption) RequestDecoder {
+	return makeBodyDecoder(prototype, decodeProtobuf, options)
+}
+
+// FormRequestDecoder decode query and form (urlencoded or multipart) params,
+// fields are matched by `form` tag or json name
+func FormRequestDecoder(prototype interface{}, options ...CodecOption) RequestDecoder {
+	mustBePointer(prototype)
+	var c = newCodecConfig(options)
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		var input = newInput(prototype)
+		if err := decodeForm(r, input, c); err != nil {
+			return nil, err
+		}
+		if err := bindPathVars(r, input); err != nil {
+			return nil, err
+		}
+		return input, nil
+	}
+}
+
+func decodeForm(r *http.Request, input interface{}, c *codecConfig) error {
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(nil, r.Body, c.bodyLimit)
+	}
+	var err error
+	if mediaType(r.Header.Get(HeaderContentTypeKey)) == ContentTypeMultipart {
+		err = r.ParseMultipartForm(c.bodyLimit)
+	} else {
+		err = r.ParseForm()
+	}
+	if err != nil {
+		return bodyReadError(err)
+	}
+	if err := bindValues(input, "form", r.Form, c.strict); err != nil {
+		return ErrInvalidRequestParam.Wrap(err, errors.Params{"error": err.Error()})
+	}
+	return nil
+}
+
+// NegotiatedRequestDecoder choose decoder by Content-Type of request,
+// JSON body of proto.Message is decoded by jsonpb and request
+// without body (e.g. GET) is decoded from query params
+func NegotiatedRequestDecoder(prototype interface{}, options ...CodecOption) RequestDecoder {
+	var (
+		_, isProto = prototype.(proto.Message)
+		jsonDecode = decodeJSON
+		decoders   = map[string]RequestDecoder{}
+	)
+	if isProto {
+		jsonDecode = decodeJSONPB
+		decoders[ContentTypeProtobuf] = makeBodyDecoder(prototype, decodeProtobuf, options)
+		decoders["application/protobuf"] = decoders[ContentTypeProtobuf]
+	}
+	decoders[ContentTypeJSON] = makeBodyDecoder(prototype, jsonDecode, options)
+	decoders[ContentTypeForm] = FormRequestDecoder(prototype, options...)
+	decoders[ContentTypeMultipart] = decoders[ContentTypeForm]
+
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		contentType := mediaType(r.Header.Get(HeaderContentTypeKey))
+		if contentType == "" {
+			if r.ContentLength > 0 {
+				contentType = ContentTypeJSON
+			} else {
+				contentType = ContentTypeForm
+			}
+		}
+		if strings.HasSuffix(contentType, "+json") {
+			contentType = ContentTypeJSON
+		}
+		decoder, ok := decoders[contentType]
+		if !ok {
+			return nil, ErrUnsupportedMediaType.New(errors.Params{"contentType": contentType})
+		}
+		return decoder(ctx, r)
+	}
+}
+
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	}
+	return t
+}
+
+type bodyEncoder func(response interface{}) ([]byte, error)
+
+func encodeJSON(response interface{}) ([]byte, error) {
+	return json.Marshal(response)
+}
+
+func encodeJSONPB(response interface{}) ([]byte, error) {
+	message, ok := response.(proto.Message)
+	if !ok {
+		return json.Marshal(response)
+	}
+	var b bytes.Buffer
+	var marshaler = jsonpb.Marshaler{}
+	if err := marshaler.Marshal(&b, message); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func encodeProtobuf(response interface{}) ([]byte, error) {
+	message, ok := response.(proto.Message)
+	if !ok {
+		return nil, errors.New(errors.ErrorTypeInternal, "response is not proto.Message")
+	}
+	return proto.Marshal(message)
+}
+
+func makeResponseEncoder(contentType string, encode bodyEncoder, options []CodecOption) ResponseEncoder {
+	var c = newCodecConfig(options)
+	return func(ctx context.Context, response interface{}) (int, []byte, error) {
+		b, err := encode(response)
+		if err != nil {
+			return http.StatusInternalServerError, nil, err
+		}
+		if w, ok := ctx.Value(ResponseWriterContextKey).(http.ResponseWriter); ok {
+			w.Header().Set(HeaderContentTypeKey, contentType)
+		}
+		return c.statusCode, b, nil
+	}
+}
+
+func JSONResponseEncoder(options ...CodecOption) ResponseEncoder {
+	return makeResponseEncoder(ContentTypeJSON, encodeJSON, options)
+}
+
+// JSONPBResponseEncoder encode proto.Message with protobuf JSON mapping,
+// other values are encoded by encoding/json
+func JSONPBResponseEncoder(options ...CodecOption) ResponseEncoder {
+	return makeResponseEncoder(ContentTypeJSON, encodeJSONPB, options)
+}
+
+func ProtobufResponseEncoder(options ...CodecOption) ResponseEncoder {
+	return makeResponseEncoder(ContentTypeProtobuf, encodeProtobuf, options)
+}
+
+// NegotiatedResponseEncoder choose encoder by Accept header of request,
+// proto.Message can be sent as protobuf, everything else is JSON
+func NegotiatedResponseEncoder(options ...CodecOption) ResponseEncoder {
+	var (
+		jsonEncoder     = JSONPBResponseEncoder(options...)
+		protobufEncoder = ProtobufResponseEncoder(options...)
+	)
+	return func(ctx context.Context, response interface{}) (int, []byte, error) {
+		if _, isProto := response.(proto.Message); isProto {
+			if r, ok := ctx.Value(RequestContextKey).(*http.Request); ok && acceptProtobuf(r.Header.Get(HeaderAcceptKey)) {
+				return protobufEncoder(ctx, response)
+			}
+		}
+		return jsonEncoder(ctx, response)
+	}
+}
+
+// acceptProtobuf report whether protobuf is preferred over JSON
+func acceptProtobuf(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		switch mediaType(part) {
+		case ContentTypeProtobuf, "application/protobuf":
+			return true
+		case ContentTypeJSON, "application/*", "*/*":
+			return false
+		}
+	}
+	return false
+}
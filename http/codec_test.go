@@ -0,0 +1,160 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/mux"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type updateUserInput struct {
+	ID    int64    `json:"id" path:"userID"`
+	Name  string   `json:"name"`
+	Tags  []string `json:"tags" form:"tag"`
+	Admin *bool    `json:"admin,omitempty"`
+}
+
+func newJSONRequest(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/users/42", strings.NewReader(body))
+	r.Header.Set(HeaderContentTypeKey, "application/json; charset=utf-8")
+	return mux.SetURLVars(r, map[string]string{"userID": "42"})
+}
+
+func TestJSONRequestDecoder(t *testing.T) {
+	var ctx = context.Background()
+
+	t.Run("decode body and path", func(t *testing.T) {
+		input, err := JSONRequestDecoder(&updateUserInput{})(ctx, newJSONRequest(`{"name": "john", "extra": 1}`))
+		assert.NoError(t, err)
+		assert.Equal(t, &updateUserInput{ID: 42, Name: "john"}, input)
+	})
+
+	t.Run("new instance for every request", func(t *testing.T) {
+		var decoder = JSONRequestDecoder(&updateUserInput{})
+		first, _ := decoder(ctx, newJSONRequest(`{"name": "john"}`))
+		second, _ := decoder(ctx, newJSONRequest(`{}`))
+		assert.True(t, first != second)
+		assert.Equal(t, "", second.(*updateUserInput).Name)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		_, err := JSONRequestDecoder(&updateUserInput{}, WithStrict())(ctx, newJSONRequest(`{"name": "john", "extra": 1}`))
+		assert.True(t, stderrors.Is(err, ErrInvalidRequestBody))
+	})
+
+	t.Run("body limit", func(t *testing.T) {
+		_, err := JSONRequestDecoder(&updateUserInput{}, WithBodyLimit(8))(ctx, newJSONRequest(`{"name": "john"}`))
+		assert.True(t, stderrors.Is(err, ErrRequestTooLarge))
+	})
+
+	t.Run("invalid path var", func(t *testing.T) {
+		r := mux.SetURLVars(newJSONRequest(`{}`), map[string]string{"userID": "abc"})
+		_, err := JSONRequestDecoder(&updateUserInput{})(ctx, r)
+		assert.True(t, stderrors.Is(err, ErrInvalidRequestParam))
+	})
+
+	t.Run("prototype must be pointer", func(t *testing.T) {
+		assert.Panics(t, func() {
+			JSONRequestDecoder(updateUserInput{})
+		})
+	})
+}
+
+func TestFormRequestDecoder(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/users?tag=a&tag=b", strings.NewReader("name=john&admin=true"))
+	r.Header.Set(HeaderContentTypeKey, ContentTypeForm)
+	input, err := FormRequestDecoder(&updateUserInput{})(context.Background(), r)
+	assert.NoError(t, err)
+	var admin = true
+	assert.Equal(t, &updateUserInput{Name: "john", Tags: []string{"a", "b"}, Admin: &admin}, input)
+
+	r = httptest.NewRequest(http.MethodGet, "/users?unknown=1", nil)
+	_, err = FormRequestDecoder(&updateUserInput{}, WithStrict())(context.Background(), r)
+	assert.True(t, stderrors.Is(err, ErrInvalidRequestParam))
+
+	r = httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("name=john&admin=true"))
+	r.Header.Set(HeaderContentTypeKey, ContentTypeForm)
+	_, err = FormRequestDecoder(&updateUserInput{}, WithBodyLimit(5))(context.Background(), r)
+	assert.True(t, stderrors.Is(err, ErrRequestTooLarge))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, errors.From(err).HTTPStatus())
+
+	var body = &bytes.Buffer{}
+	var form = multipart.NewWriter(body)
+	_ = form.WriteField("name", "john")
+	_ = form.Close()
+	r = httptest.NewRequest(http.MethodPost, "/users", body)
+	r.Header.Set(HeaderContentTypeKey, form.FormDataContentType())
+	_, err = FormRequestDecoder(&updateUserInput{}, WithBodyLimit(5))(context.Background(), r)
+	assert.True(t, stderrors.Is(err, ErrRequestTooLarge))
+}
+
+func TestNegotiatedRequestDecoder(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		decoder = NegotiatedRequestDecoder(&errdetails.ErrorInfo{})
+	)
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"reason": "A", "metadata": {"k": "v"}}`))
+	r.Header.Set(HeaderContentTypeKey, ContentTypeJSON)
+	input, err := decoder(ctx, r)
+	assert.NoError(t, err)
+	assert.Equal(t, "v", input.(*errdetails.ErrorInfo).Metadata["k"])
+
+	b, _ := proto.Marshal(&errdetails.ErrorInfo{Reason: "B"})
+	r = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(b))
+	r.Header.Set(HeaderContentTypeKey, ContentTypeProtobuf)
+	input, err = decoder(ctx, r)
+	assert.NoError(t, err)
+	assert.Equal(t, "B", input.(*errdetails.ErrorInfo).Reason)
+
+	r = httptest.NewRequest(http.MethodGet, "/?reason=C", nil)
+	input, err = decoder(ctx, r)
+	assert.NoError(t, err)
+	assert.Equal(t, "C", input.(*errdetails.ErrorInfo).Reason)
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`<xml/>`))
+	r.Header.Set(HeaderContentTypeKey, "text/xml")
+	_, err = decoder(ctx, r)
+	assert.True(t, stderrors.Is(err, ErrUnsupportedMediaType))
+}
+
+func TestNegotiatedResponseEncoder(t *testing.T) {
+	var encode = func(accept string, response interface{}) (int, []byte, string) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(HeaderAcceptKey, accept)
+		w := httptest.NewRecorder()
+		ctx := context.WithValue(context.Background(), RequestContextKey, r)
+		ctx = context.WithValue(ctx, ResponseWriterContextKey, http.ResponseWriter(w))
+		code, b, err := NegotiatedResponseEncoder(WithStatusCode(http.StatusCreated))(ctx, response)
+		assert.NoError(t, err)
+		return code, b, w.Header().Get(HeaderContentTypeKey)
+	}
+
+	code, b, contentType := encode("", &errdetails.ErrorInfo{Reason: "A"})
+	assert.Equal(t, http.StatusCreated, code)
+	assert.JSONEq(t, `{"reason": "A"}`, string(b))
+	assert.Equal(t, ContentTypeJSON, contentType)
+
+	_, b, contentType = encode("application/x-protobuf, application/json;q=0.9", &errdetails.ErrorInfo{Reason: "A"})
+	assert.Equal(t, ContentTypeProtobuf, contentType)
+	var decoded errdetails.ErrorInfo
+	assert.NoError(t, proto.Unmarshal(b, &decoded))
+	assert.Equal(t, "A", decoded.Reason)
+
+	_, b, contentType = encode(ContentTypeProtobuf, map[string]int{"a": 1})
+	assert.Equal(t, ContentTypeJSON, contentType, "only proto.Message can be sent as protobuf")
+	assert.JSONEq(t, `{"a": 1}`, string(b))
+}
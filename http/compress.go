@@ -0,0 +1,246 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"github.com/andybalholm/brotli"
+	"github.com/octofoxio/foundation/errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	HeaderAcceptEncodingKey  = "Accept-Encoding"
+	HeaderContentEncodingKey = "Content-Encoding"
+
+	EncodingGzip    = "gzip"
+	EncodingBrotli  = "br"
+	EncodingDeflate = "deflate"
+)
+
+var ErrUnsupportedEncoding = errors.Define("UNSUPPORTED_CONTENT_ENCODING", errors.ErrorTypeBadInput, "content encoding {encoding} is not supported", "Request body is compressed by encoding which server cannot decompress")
+
+// CompressOptions is configuration of CompressMiddleware, zero levels
+// are default levels of each encoding. Response which Content-Type
+// start with one of SkipContentTypes is sent as-is
+type CompressOptions struct {
+	GzipLevel        int
+	BrotliLevel      int
+	SkipContentTypes []string
+}
+
+var defaultSkipContentTypes = []string{
+	"image/", "video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/octet-stream", "application/x-protobuf",
+}
+
+// CompressMiddleware compress response by brotli or gzip following
+// Accept-Encoding of request, brotli is preferred when client accept both
+func CompressMiddleware(options CompressOptions) Middleware {
+	if options.GzipLevel == 0 {
+		options.GzipLevel = gzip.DefaultCompression
+	}
+	if options.BrotliLevel == 0 {
+		options.BrotliLevel = brotli.DefaultCompression
+	}
+	if options.SkipContentTypes == nil {
+		options.SkipContentTypes = defaultSkipContentTypes
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context) (interface{}, error) {
+			var (
+				r = getRequestFromContext(ctx)
+				w = getResponseWriterFromContext(ctx)
+			)
+			w.Header().Add("Vary", HeaderAcceptEncodingKey)
+			var encoding = negotiateEncoding(r.Header.Get(HeaderAcceptEncodingKey))
+			if encoding == "" || r.Method == http.MethodHead {
+				return next(ctx)
+			}
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, options: options}
+			defer cw.Close()
+			return next(withResponseWriter(ctx, cw))
+		}
+	}
+}
+
+// negotiateEncoding choose br or gzip from Accept-Encoding by quality
+func negotiateEncoding(acceptEncoding string) string {
+	var (
+		best    string
+		quality float64
+	)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		var fields = strings.Split(part, ";")
+		var encoding = strings.ToLower(strings.TrimSpace(fields[0]))
+		if encoding != EncodingBrotli && encoding != EncodingGzip {
+			continue
+		}
+		var q = 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > quality || (q == quality && encoding == EncodingBrotli) {
+			best, quality = encoding, q
+		}
+	}
+	return best
+}
+
+// compressWriter decide to compress when header is written,
+// so handler can still set Content-Encoding or Content-Type before that
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	options  CompressOptions
+	decided  bool
+	encoder  io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if !w.decided {
+		w.decided = true
+		if w.shouldCompress(code) {
+			var h = w.Header()
+			h.Set(HeaderContentEncodingKey, w.encoding)
+			h.Del("Content-Length")
+			if w.encoding == EncodingBrotli {
+				w.encoder = brotli.NewWriterLevel(w.ResponseWriter, w.options.BrotliLevel)
+			} else {
+				w.encoder, _ = gzip.NewWriterLevel(w.ResponseWriter, w.options.GzipLevel)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *compressWriter) shouldCompress(code int) bool {
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		return false
+	}
+	var h = w.Header()
+	if h.Get(HeaderContentEncodingKey) != "" {
+		return false
+	}
+	var contentType = strings.ToLower(h.Get(HeaderContentTypeKey))
+	for _, skip := range w.options.SkipContentTypes {
+		if strings.HasPrefix(contentType, skip) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		if w.Header().Get(HeaderContentTypeKey) == "" {
+			w.Header().Set(HeaderContentTypeKey, http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.encoder == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.encoder.Write(b)
+}
+
+type encoderFlusher interface {
+	Flush() error
+}
+
+// Flush send compressed data which is written so far, e.g. for SSE
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.encoder.(encoderFlusher); ok {
+		_ = f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack pass connection as-is, e.g. for WebSocket
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Close flush compressed data, handler is completed
+func (w *compressWriter) Close() error {
+	if w.encoder == nil {
+		return nil
+	}
+	return w.encoder.Close()
+}
+
+// DecompressMiddleware decompress request body which is sent with
+// Content-Encoding gzip, br or deflate, so decoders read plain body.
+// Put BodyLimitMiddleware before it to limit the decompressed size
+//
+// ```
+// s.Post("/upload", handler, http.BodyLimitMiddleware(10<<20), http.DecompressMiddleware())
+// ```
+func DecompressMiddleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context) (interface{}, error) {
+			var r = getRequestFromContext(ctx)
+			var encoding = strings.ToLower(strings.TrimSpace(r.Header.Get(HeaderContentEncodingKey)))
+			if encoding == "" || encoding == "identity" || r.Body == nil || r.Body == http.NoBody {
+				return next(ctx)
+			}
+			var body io.ReadCloser
+			switch encoding {
+			case EncodingGzip, "x-gzip":
+				gz, err := gzip.NewReader(r.Body)
+				if err != nil {
+					return nil, ErrInvalidRequestBody.Wrap(err, errors.Params{"error": err.Error()})
+				}
+				body = gz
+			case EncodingBrotli:
+				body = ioutil.NopCloser(brotli.NewReader(r.Body))
+			case EncodingDeflate:
+				// HTTP deflate is zlib format
+				zr, err := zlib.NewReader(r.Body)
+				if err != nil {
+					return nil, ErrInvalidRequestBody.Wrap(err, errors.Params{"error": err.Error()})
+				}
+				body = zr
+			default:
+				return nil, ErrUnsupportedEncoding.New(errors.Params{"encoding": encoding})
+			}
+			defer body.Close()
+
+			req := r.Clone(r.Context())
+			req.Body = body
+			req.ContentLength = -1
+			req.Header.Del(HeaderContentEncodingKey)
+			req.Header.Del("Content-Length")
+			return next(withRequest(ctx, req))
+		}
+	}
+}
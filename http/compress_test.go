@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	assert.Equal(t, "br", negotiateEncoding("gzip, deflate, br"))
+	assert.Equal(t, "gzip", negotiateEncoding("gzip;q=1.0, br;q=0.5"))
+	assert.Equal(t, "", negotiateEncoding("identity"))
+	assert.Equal(t, "", negotiateEncoding("gzip;q=0"))
+}
+
+func TestCompressMiddleware(t *testing.T) {
+	var body = strings.Repeat(`{"message": "hello"}`, 100)
+	var s = NewServer(WithMiddleware(CompressMiddleware(CompressOptions{})))
+	s.Get("/json", func(ctx context.Context) (interface{}, error) {
+		getResponseWriterFromContext(ctx).Header().Set(HeaderContentTypeKey, ContentTypeJSON)
+		return nil, writeResponse(ctx, http.StatusOK, []byte(body))
+	})
+	s.Get("/image", func(ctx context.Context) (interface{}, error) {
+		getResponseWriterFromContext(ctx).Header().Set(HeaderContentTypeKey, "image/png")
+		return nil, writeResponse(ctx, http.StatusOK, []byte(body))
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/json", nil)
+	r.Header.Set(HeaderAcceptEncodingKey, "gzip")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, "gzip", w.Header().Get(HeaderContentEncodingKey))
+	assert.Equal(t, HeaderAcceptEncodingKey, w.Header().Get("Vary"))
+	gz, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+	b, _ := ioutil.ReadAll(gz)
+	assert.Equal(t, body, string(b))
+
+	r = httptest.NewRequest(http.MethodGet, "/json", nil)
+	r.Header.Set(HeaderAcceptEncodingKey, "gzip, br")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, "br", w.Header().Get(HeaderContentEncodingKey))
+	b, _ = ioutil.ReadAll(brotli.NewReader(w.Body))
+	assert.Equal(t, body, string(b))
+
+	r = httptest.NewRequest(http.MethodGet, "/image", nil)
+	r.Header.Set(HeaderAcceptEncodingKey, "gzip")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Empty(t, w.Header().Get(HeaderContentEncodingKey))
+	assert.Equal(t, body, w.Body.String())
+
+	// error response is written by server after middleware
+	r = httptest.NewRequest(http.MethodGet, "/missing", nil)
+	r.Header.Set(HeaderAcceptEncodingKey, "gzip")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get(HeaderContentEncodingKey))
+	assert.Contains(t, w.Body.String(), ErrRouteNotFound.Reason)
+}
+
+func TestDecompressMiddleware(t *testing.T) {
+	var s = NewServer()
+	s.Post("/echo", func(ctx context.Context) (interface{}, error) {
+		b, err := readBody(getRequestFromContext(ctx), newCodecConfig(nil))
+		if err != nil {
+			return nil, err
+		}
+		return nil, writeResponse(ctx, http.StatusOK, b)
+	}, BodyLimitMiddleware(10), DecompressMiddleware())
+
+	var gzipped, zipped, brotlied bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	_, _ = gw.Write([]byte("hello"))
+	_ = gw.Close()
+	zw := zlib.NewWriter(&zipped)
+	_, _ = zw.Write([]byte("hello"))
+	_ = zw.Close()
+	bw := brotli.NewWriter(&brotlied)
+	_, _ = bw.Write([]byte(strings.Repeat("a", 100)))
+	_ = bw.Close()
+
+	for encoding, body := range map[string][]byte{"gzip": gzipped.Bytes(), "deflate": zipped.Bytes()} {
+		r := httptest.NewRequest(http.MethodPost, "/echo", bytes.NewReader(body))
+		r.Header.Set(HeaderContentEncodingKey, encoding)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code, encoding)
+		assert.Equal(t, "hello", w.Body.String(), encoding)
+	}
+
+	// limit is applied to decompressed body
+	r := httptest.NewRequest(http.MethodPost, "/echo", bytes.NewReader(brotlied.Bytes()))
+	r.Header.Set(HeaderContentEncodingKey, "br")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), ErrRequestTooLarge.Reason)
+
+	r = httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("hello"))
+	r.Header.Set(HeaderContentEncodingKey, "compress")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), ErrUnsupportedEncoding.Reason)
+}
@@ -22,3 +22,27 @@ func getResponseWriterFromContext(c context.Context) http.ResponseWriter {
 	}
 	panic("Cannot get response writer object from context, this is fatal error please ensure you are doing right")
 }
+
+// withRequest replace request of handlers after middleware,
+// e.g. request which body is decompressed
+func withRequest(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, RequestContextKey, r)
+}
+
+// withResponseWriter replace response writer of handlers after middleware,
+// e.g. writer which compress response
+func withResponseWriter(ctx context.Context, w http.ResponseWriter) context.Context {
+	return context.WithValue(ctx, ResponseWriterContextKey, w)
+}
+
+// PathVars return path variables of the route (e.g. {id} in "/users/{id}")
+func PathVars(ctx context.Context) map[string]string {
+	if vars, ok := ctx.Value(PathVarsContextKey).(map[string]string); ok {
+		return vars
+	}
+	return map[string]string{}
+}
+
+func PathVar(ctx context.Context, name string) string {
+	return PathVars(ctx)[name]
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var defaultCORSMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost,
+	http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// CORSOptions is configuration of CORSMiddleware, origin can be "*",
+// exact origin or origin with wildcard subdomain (e.g. "https://*.octofox.io").
+// AllowedHeaders default to headers which browser ask in preflight.
+// "*" cannot be used with AllowCredentials since any site could read
+// response with user cookies, list the origins (or wildcard subdomain) instead
+type CORSOptions struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+func (o CORSOptions) allowOrigin(origin string) bool {
+	for _, allowed := range o.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if i := strings.Index(allowed, "*"); i >= 0 {
+			prefix, suffix := allowed[:i], allowed[i+1:]
+			if len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CORSMiddleware add CORS headers to response of allowed origins
+// and answer preflight request without calling next handler.
+// Preflight request does not match route which not accept OPTIONS,
+// so use it with WithMiddleware or HTTPMiddleware instead of route middleware
+//
+// ```
+// s := http.NewServer(http.WithMiddleware(http.CORSMiddleware(http.CORSOptions{
+// AllowedOrigins: []string{"https://*.octofox.io"},
+// })))
+// ```
+func CORSMiddleware(options CORSOptions) Middleware {
+	if options.AllowCredentials && contains(options.AllowedOrigins, "*") {
+		panic(`CORS origin "*" cannot be used with AllowCredentials`)
+	}
+	if len(options.AllowedMethods) == 0 {
+		options.AllowedMethods = defaultCORSMethods
+	}
+	var (
+		methods = strings.Join(options.AllowedMethods, ", ")
+		headers = strings.Join(options.AllowedHeaders, ", ")
+		exposed = strings.Join(options.ExposedHeaders, ", ")
+	)
+	return func(next Handler) Handler {
+		return func(ctx context.Context) (interface{}, error) {
+			var (
+				r      = getRequestFromContext(ctx)
+				w      = getResponseWriterFromContext(ctx)
+				origin = r.Header.Get("Origin")
+			)
+			w.Header().Add("Vary", "Origin")
+			if origin == "" || !options.allowOrigin(origin) {
+				return next(ctx)
+			}
+			if contains(options.AllowedOrigins, "*") {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			if options.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			var requestMethod = r.Header.Get("Access-Control-Request-Method")
+			if r.Method != http.MethodOptions || requestMethod == "" {
+				if exposed != "" {
+					w.Header().Set("Access-Control-Expose-Headers", exposed)
+				}
+				return next(ctx)
+			}
+
+			// preflight
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			if !contains(options.AllowedMethods, requestMethod) {
+				w.WriteHeader(http.StatusNoContent)
+				return nil, nil
+			}
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			if headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				w.Header().Set("Access-Control-Allow-Headers", requested)
+			}
+			if options.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(options.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return nil, nil
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	var s = NewServer(WithMiddleware(CORSMiddleware(CORSOptions{
+		AllowedOrigins:   []string{"https://app.octofox.io", "https://*.octofox.dev"},
+		ExposedHeaders:   []string{HeaderRequestIDKey},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})))
+	s.Get("/users", func(ctx context.Context) (interface{}, error) {
+		return nil, writeResponse(ctx, http.StatusOK, []byte("[]"))
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/users", nil)
+	r.Header.Set("Origin", "https://app.octofox.io")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://app.octofox.io", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, HeaderRequestIDKey, w.Header().Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+
+	// preflight of route which does not accept OPTIONS
+	r = httptest.NewRequest(http.MethodOptions, "/users", nil)
+	r.Header.Set("Origin", "https://staging.octofox.dev")
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	r.Header.Set("Access-Control-Request-Headers", "Authorization, Content-Type")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://staging.octofox.dev", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, HEAD, POST, PUT, PATCH, DELETE", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Authorization, Content-Type", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+
+	r = httptest.NewRequest(http.MethodGet, "/users", nil)
+	r.Header.Set("Origin", "https://evil.com")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	t.Run("any origin", func(t *testing.T) {
+		var s = NewServer(WithMiddleware(CORSMiddleware(CORSOptions{AllowedOrigins: []string{"*"}})))
+		s.Get("/users", func(ctx context.Context) (interface{}, error) {
+			return nil, writeResponse(ctx, http.StatusOK, []byte("[]"))
+		})
+		r := httptest.NewRequest(http.MethodGet, "/users", nil)
+		r.Header.Set("Origin", "https://evil.com")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("any origin cannot allow credentials", func(t *testing.T) {
+		assert.Panics(t, func() {
+			CORSMiddleware(CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+		})
+	})
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	var handler = HTTPMiddleware(CORSMiddleware(CORSOptions{AllowedOrigins: []string{"*"}}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Origin", "https://app.octofox.io")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+
+	handler = HTTPMiddleware(BodyLimitMiddleware(1))(http.NotFoundHandler())
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too large")))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), ErrRequestTooLarge.Reason)
+}
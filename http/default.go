@@ -6,26 +6,103 @@ package http
 
 import (
 	"context"
+	"fmt"
 	"github.com/gorilla/mux"
-	_ "github.com/gorilla/mux"
-	"github.com/rs/xid"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/octofoxio/foundation/logger"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
 const (
-	HeaderRequestIDKey     = "RequestID"
-	HeaderAuthorizationKey = "Authorization"
+	HeaderRequestIDKey      = "RequestID"
+	HeaderAuthorizationKey  = "Authorization"
+	HeaderAcceptLanguageKey = "Accept-Language"
 
 	RequestContextKey        = "request"
 	ResponseWriterContextKey = "response"
 	InputBodyContextKey      = "input"
+	PathVarsContextKey       = "pathvars"
+)
+
+var (
+	ErrRouteNotFound    = errors.Define("ROUTE_NOT_FOUND", errors.ErrorTypeNotfound, "route {method} {path} not found", "There is no route for the path")
+	ErrMethodNotAllowed = errors.Define("METHOD_NOT_ALLOWED", errors.ErrorTypeMethodNotAllowed, "method {method} is not allowed for {path}", "Path exists but it does not accept the method, see Allow header")
 )
 
 type Server struct {
-	r *mux.Router
+	r                       *mux.Router
+	root                    *Server
+	prefix                  string
+	middleware              []Middleware
+	reporters               []logger.Reporter
+	errorEncoder            ErrorEncoder
+	disableAccessLog        bool
+	notFoundHandler         Handler
+	methodNotAllowedHandler Handler
+	docs                    *routeDocs
+	webSockets              *webSockets
+}
+
+type ServerOption func(s *Server)
+
+// WithErrorEncoder replace JSONErrorEncoder of every routes,
+// use ErrorEncoderMiddleware to replace it for a route
+func WithErrorEncoder(encoder ErrorEncoder) ServerOption {
+	return func(s *Server) {
+		s.errorEncoder = encoder
+	}
+}
+
+// WithMiddleware add middleware to every routes, including
+// not found and method not allowed handlers (e.g. CORSMiddleware
+// which must answer preflight request of any paths)
+func WithMiddleware(middleware ...Middleware) ServerOption {
+	return func(s *Server) {
+		s.middleware = append(s.middleware, middleware...)
+	}
+}
+
+// WithReporter send panics recovered from handlers to reporter
+func WithReporter(reporter logger.Reporter) ServerOption {
+	return func(s *Server) {
+		s.reporters = append(s.reporters, reporter)
+	}
+}
+
+// WithoutAccessLog stop logging a line of every requests,
+// e.g. when access log is written by load balancer
+func WithoutAccessLog() ServerOption {
+	return func(s *Server) {
+		s.disableAccessLog = true
+	}
+}
+
+// WithNotFoundHandler replace handler of request which match no routes,
+// the default one return ErrRouteNotFound
+func WithNotFoundHandler(handler Handler) ServerOption {
+	return func(s *Server) {
+		s.notFoundHandler = handler
+	}
+}
+
+// WithMethodNotAllowedHandler replace handler of request which match
+// path of a route but not its method, the default one return ErrMethodNotAllowed
+func WithMethodNotAllowedHandler(handler Handler) ServerOption {
+	return func(s *Server) {
+		s.methodNotAllowedHandler = handler
+	}
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if s.root != s {
+		s.root.ServeHTTP(w, req)
+		return
+	}
+	// request ID is set here instead of router middleware
+	// so 404 and 405 responses have it as well
+	req = withRequestID(w, req)
 	s.r.ServeHTTP(w, req)
 }
 
@@ -40,40 +117,131 @@ func EndpointHandler(endpoint EndpointFunc) Handler {
 	}
 }
 
-func (s *Server) registerHTTPHandler(method string, path string, handler Handler, middleware ...Middleware) {
-	s.r.Methods(method).Path(path).
-		HandlerFunc(execute(method, path, handler, middleware...))
+// registerHTTPHandler add route, empty method match every methods,
+// middleware of groups are applied outside middleware of the route
+func (s *Server) registerHTTPHandler(method string, path string, handler Handler, middleware ...Middleware) *mux.Route {
+	route := s.r.Path(path)
+	if method != "" {
+		route = route.Methods(method)
+	}
+	route.HandlerFunc(s.handlerFunc(method, s.prefix+path, handler, middleware...))
+	return route
+}
+
+func (s *Server) handlerFunc(method string, path string, handler Handler, middleware ...Middleware) http.HandlerFunc {
+	middleware = append(append(middleware, s.middleware...), RecoveryMiddleware(s.reporters...))
+	var config = executeConfig{errorEncoder: s.errorEncoder, accessLog: !s.disableAccessLog}
+	return executeWithConfig(config, method, path, handler, middleware...)
+}
+
+func (s *Server) Get(path string, handler Handler, middleware ...Middleware) *mux.Route {
+	return s.registerHTTPHandler(http.MethodGet, path, handler, middleware...)
+}
+func (s *Server) Post(path string, handler Handler, middleware ...Middleware) *mux.Route {
+	return s.registerHTTPHandler(http.MethodPost, path, handler, middleware...)
+}
+func (s *Server) Put(path string, handler Handler, middleware ...Middleware) *mux.Route {
+	return s.registerHTTPHandler(http.MethodPut, path, handler, middleware...)
+}
+func (s *Server) Patch(path string, handler Handler, middleware ...Middleware) *mux.Route {
+	return s.registerHTTPHandler(http.MethodPatch, path, handler, middleware...)
+}
+func (s *Server) Delete(path string, handler Handler, middleware ...Middleware) *mux.Route {
+	return s.registerHTTPHandler(http.MethodDelete, path, handler, middleware...)
+}
+func (s *Server) Head(path string, handler Handler, middleware ...Middleware) *mux.Route {
+	return s.registerHTTPHandler(http.MethodHead, path, handler, middleware...)
+}
+func (s *Server) Options(path string, handler Handler, middleware ...Middleware) *mux.Route {
+	return s.registerHTTPHandler(http.MethodOptions, path, handler, middleware...)
+}
+
+// Any register handler for every methods
+func (s *Server) Any(path string, handler Handler, middleware ...Middleware) *mux.Route {
+	return s.registerHTTPHandler("", path, handler, middleware...)
+}
+
+// Group create routes which share path prefix and middleware,
+// middleware of group run before middleware of each routes
+//
+// ```
+// api := s.Group("/api/v1", authMiddleware)
+// api.Get("/users/{id}", getUser).Name("user")
+// ```
+func (s *Server) Group(prefix string, middleware ...Middleware) *Server {
+	group := s.subrouter(s.r.PathPrefix(prefix).Subrouter(), middleware)
+	group.prefix = s.prefix + prefix
+	return group
+}
+
+// Host create group of routes which match host template (e.g. "{tenant}.example.com")
+func (s *Server) Host(template string, middleware ...Middleware) *Server {
+	return s.subrouter(s.r.Host(template).Subrouter(), middleware)
 }
-func (s *Server) Get(path string, handler Handler, middleware ...Middleware) {
-	s.registerHTTPHandler("Get", path, handler, middleware...)
+
+// Headers create group of routes which match header values,
+// pairs are header name and value (e.g. "X-Api-Version", "2")
+func (s *Server) Headers(pairs ...string) *Server {
+	return s.subrouter(s.r.Headers(pairs...).Subrouter(), nil)
 }
-func (s *Server) Post(path string, handler Handler, middleware ...Middleware) {
-	s.registerHTTPHandler("Post", path, handler, middleware...)
+
+func (s *Server) subrouter(r *mux.Router, middleware []Middleware) *Server {
+	var group = *s
+	group.r = r
+	group.middleware = append(append([]Middleware{}, middleware...), s.middleware...)
+	return &group
 }
-func (s *Server) Put(path string, handler Handler, middleware ...Middleware) {
-	s.registerHTTPHandler("Put", path, handler, middleware...)
+
+// URL build URL of named route, pairs are path variables
+// and values (e.g. "id", "42")
+func (s *Server) URL(name string, pairs ...string) (*url.URL, error) {
+	route := s.root.r.Get(name)
+	if route == nil {
+		return nil, fmt.Errorf("route %s not found", name)
+	}
+	return route.URL(pairs...)
 }
-func (s *Server) Delete(path string, handler Handler, middleware ...Middleware) {
-	s.registerHTTPHandler("Delete", path, handler, middleware...)
+
+var methods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
 }
 
-func NewServer() *Server {
-	r := mux.NewRouter()
+// allowedMethods return methods which has route for path of r
+func (s *Server) allowedMethods(r *http.Request) []string {
+	var allowed []string
+	for _, method := range methods {
+		req := r.Clone(r.Context())
+		req.Method = method
+		var match mux.RouteMatch
+		if s.r.Match(req, &match) && match.MatchErr == nil {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
 
-	// Mess with request ID and recovery
-	r.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			var requestID string
-			if id := w.Header().Get("x-amzn-RequestId"); requestID != "" {
-				requestID = id
-			} else {
-				requestID = xid.New().String()
-			}
-			w.Header().Set(HeaderRequestIDKey, requestID)
-			next.ServeHTTP(w, r)
-		})
-	})
-	return &Server{
-		r: r,
+func NewServer(options ...ServerOption) *Server {
+	var s = &Server{
+		r:            mux.NewRouter(),
+		errorEncoder: JSONErrorEncoder,
+		docs:         newRouteDocs(),
+		webSockets:   newWebSockets(),
+		notFoundHandler: func(ctx context.Context) (interface{}, error) {
+			r := getRequestFromContext(ctx)
+			return nil, ErrRouteNotFound.New(errors.Params{"method": r.Method, "path": r.URL.Path})
+		},
+	}
+	s.root = s
+	s.methodNotAllowedHandler = func(ctx context.Context) (interface{}, error) {
+		r := getRequestFromContext(ctx)
+		getResponseWriterFromContext(ctx).Header().Set("Allow", strings.Join(s.allowedMethods(r), ", "))
+		return nil, ErrMethodNotAllowed.New(errors.Params{"method": r.Method, "path": r.URL.Path})
+	}
+	for _, option := range options {
+		option(s)
 	}
+	s.r.NotFoundHandler = s.handlerFunc("", "", s.notFoundHandler)
+	s.r.MethodNotAllowedHandler = s.handlerFunc("", "", s.methodNotAllowedHandler)
+	return s
 }
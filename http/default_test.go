@@ -5,9 +5,124 @@
 package http
 
 import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
 func TestNewHTTPServer(t *testing.T) {
 
 }
+
+func textHandler(text string) Handler {
+	return func(ctx context.Context) (interface{}, error) {
+		w := getResponseWriterFromContext(ctx)
+		_, err := w.Write([]byte(text))
+		return nil, err
+	}
+}
+
+func serve(s http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestServerMethods(t *testing.T) {
+	var s = NewServer()
+	s.Get("/r", textHandler("get"))
+	s.Post("/r", textHandler("post"))
+	s.Put("/r", textHandler("put"))
+	s.Patch("/r", textHandler("patch"))
+	s.Delete("/r", textHandler("delete"))
+	s.Head("/r", textHandler(""))
+	s.Options("/r", textHandler("options"))
+	s.Any("/any", func(ctx context.Context) (interface{}, error) {
+		_, err := getResponseWriterFromContext(ctx).Write([]byte(getRequestFromContext(ctx).Method))
+		return nil, err
+	})
+
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions} {
+		w := serve(s, httptest.NewRequest(method, "/r", nil))
+		assert.Equal(t, http.StatusOK, w.Code, method)
+		assert.Equal(t, strings.ToLower(method), w.Body.String())
+	}
+	assert.Equal(t, http.StatusOK, serve(s, httptest.NewRequest(http.MethodHead, "/r", nil)).Code)
+	assert.Equal(t, "PATCH", serve(s, httptest.NewRequest(http.MethodPatch, "/any", nil)).Body.String())
+}
+
+func TestServerGroup(t *testing.T) {
+	var calls []string
+	var track = func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context) (interface{}, error) {
+				calls = append(calls, name)
+				return next(ctx)
+			}
+		}
+	}
+	var s = NewServer()
+	api := s.Group("/api", track("api"))
+	v1 := api.Group("/v1", track("v1"))
+	v1.Get("/users/{id}", func(ctx context.Context) (interface{}, error) {
+		_, err := getResponseWriterFromContext(ctx).Write([]byte(PathVar(ctx, "id")))
+		return nil, err
+	}, track("route")).Name("user")
+
+	w := serve(s, httptest.NewRequest(http.MethodGet, "/api/v1/users/42", nil))
+	assert.Equal(t, "42", w.Body.String())
+	assert.Equal(t, []string{"api", "v1", "route"}, calls)
+
+	u, err := s.URL("user", "id", "7")
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/v1/users/7", u.String())
+	_, err = v1.URL("unknown")
+	assert.Error(t, err)
+}
+
+func TestServerMatchers(t *testing.T) {
+	var s = NewServer()
+	s.Host("{tenant}.example.com").Get("/", func(ctx context.Context) (interface{}, error) {
+		_, err := getResponseWriterFromContext(ctx).Write([]byte(PathVar(ctx, "tenant")))
+		return nil, err
+	})
+	s.Headers("X-Api-Version", "2").Get("/version", textHandler("v2"))
+	s.Get("/version", textHandler("v1"))
+	s.Get("/json", textHandler("json")).Headers("Content-Type", "application/json")
+
+	r := httptest.NewRequest(http.MethodGet, "http://acme.example.com/", nil)
+	assert.Equal(t, "acme", serve(s, r).Body.String())
+
+	r = httptest.NewRequest(http.MethodGet, "/version", nil)
+	assert.Equal(t, "v1", serve(s, r).Body.String())
+	r.Header.Set("X-Api-Version", "2")
+	assert.Equal(t, "v2", serve(s, r).Body.String())
+
+	assert.Equal(t, http.StatusNotFound, serve(s, httptest.NewRequest(http.MethodGet, "/json", nil)).Code)
+}
+
+func TestServerNotFound(t *testing.T) {
+	var s = NewServer()
+	s.Get("/users", textHandler("users"))
+	s.Post("/users", textHandler("users"))
+
+	w := serve(s, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	var response ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "ROUTE_NOT_FOUND", response.Reason)
+	assert.NotEmpty(t, response.RequestID)
+
+	w = serve(s, httptest.NewRequest(http.MethodDelete, "/users", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, POST", w.Header().Get("Allow"))
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "METHOD_NOT_ALLOWED", response.Reason)
+
+	s = NewServer(WithNotFoundHandler(textHandler("custom")))
+	assert.Equal(t, "custom", serve(s, httptest.NewRequest(http.MethodGet, "/unknown", nil)).Body.String())
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/octofoxio/foundation"
+	"github.com/octofoxio/foundation/errors"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// ErrorEncoder write response of error returned from handler,
+// it is not called when handler already wrote the response
+type ErrorEncoder func(ctx context.Context, w http.ResponseWriter, err error)
+
+// ErrorResponse is JSON envelope of every error responses,
+// code is HTTP status (which is also errors.ErrorType) and reason
+// is stable error reason (see errors.Define)
+//
+// ```
+// {
+// "code": 400,
+// "reason": "INVALID_REGISTRATION",
+// "message": "invalid registration",
+// "details": ["email"],
+// "fieldViolations": [{"field": "email", "description": "must be an email"}],
+// "localizedMessage": {"locale": "th", "message": "ข้อมูลไม่ถูกต้อง"},
+// "requestId": "bq5c3v6e9rt1b1ofljsg",
+// "debug": ["main.handler\n\t/app/main.go:42"]
+// }
+// ```
+type ErrorResponse struct {
+	Code             int                      `json:"code"`
+	Reason           string                   `json:"reason"`
+	Message          string                   `json:"message"`
+	Details          []string                 `json:"details"`
+	FieldViolations  []errors.FieldViolation  `json:"fieldViolations,omitempty"`
+	LocalizedMessage *errors.LocalizedMessage `json:"localizedMessage,omitempty"`
+	RequestID        string                   `json:"requestId,omitempty"`
+	Debug            interface{}              `json:"debug,omitempty"`
+}
+
+// NewErrorResponse build envelope from any error, *errors.Error,
+// foundationerrorv2.Error and gRPC status errors keep their code,
+// everything else is internal error. Debug information is included
+// only when caller is allowed by foundation.DebugPolicy, otherwise
+// message of internal error is replaced with generic message
+func NewErrorResponse(ctx context.Context, err error) *ErrorResponse {
+	var (
+		e         = errors.From(err)
+		requestID = requestIDFromContext(ctx)
+		debug     interface{}
+	)
+	if foundation.AllowDebug(ctx) {
+		debug = e.GetDebug()
+	} else {
+		e = e.Sanitize(requestID)
+	}
+	var details = e.GetDetail()
+	if details == nil {
+		details = []string{}
+	}
+	return &ErrorResponse{
+		Code:             e.HTTPStatus(),
+		Reason:           e.Reason(),
+		Message:          e.Error(),
+		Details:          details,
+		FieldViolations:  e.GetFieldViolations(),
+		LocalizedMessage: e.GetLocalizedMessage(),
+		RequestID:        requestID,
+		Debug:            debug,
+	}
+}
+
+// requestIDFromContext prefer request ID in response header
+// since it is the one client receive
+func requestIDFromContext(ctx context.Context) string {
+	if w, ok := ctx.Value(ResponseWriterContextKey).(http.ResponseWriter); ok {
+		if requestID := w.Header().Get(HeaderRequestIDKey); requestID != "" {
+			return requestID
+		}
+	}
+	return foundation.GetRequestIDFromContext(ctx)
+}
+
+// JSONErrorEncoder is the default ErrorEncoder, it write ErrorResponse
+// with HTTP status of the error and Retry-After header when error has retry delay
+func JSONErrorEncoder(ctx context.Context, w http.ResponseWriter, err error) {
+	var response = NewErrorResponse(ctx, err)
+	b, marshalErr := json.Marshal(response)
+	if marshalErr != nil {
+		foundation.GetLoggerFromContext(ctx).WithError(marshalErr).Error("Error response encoding error")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if delay := errors.From(err).GetRetryDelay(); delay > 0 {
+		w.Header().Set(HeaderRetryAfterKey, strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(response.Code)
+	_, _ = w.Write(b)
+}
+
+// ErrorEncoderMiddleware write error with encoder instead of the server one,
+// the error is still returned to outer middleware
+//
+// ```
+// s.Get("/legacy", handler, http.ErrorEncoderMiddleware(legacyErrorEncoder))
+// ```
+func ErrorEncoderMiddleware(encoder ErrorEncoder) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context) (i interface{}, e error) {
+			output, err := next(ctx)
+			if err != nil {
+				encodeError(ctx, encoder, err)
+			}
+			return output, err
+		}
+	}
+}
+
+func encodeError(ctx context.Context, encoder ErrorEncoder, err error) {
+	w := getResponseWriterFromContext(ctx)
+	if rw, ok := w.(*responseWriter); ok && rw.Written() {
+		return
+	}
+	encoder(ctx, w, err)
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/octofoxio/foundation"
+	"github.com/octofoxio/foundation/errors"
+	foundationerrorv2 "github.com/octofoxio/foundation/errors/v2"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestErrorEnvelope(t *testing.T) {
+	var s = NewServer()
+	s.Post("/users", func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New(errors.ErrorTypeBadInput, "invalid registration").
+			WithReason("INVALID_REGISTRATION").
+			WithDetail("email").
+			WithFieldViolation("email", "must be an email").
+			WithRetryDelay(1500 * time.Millisecond)
+	})
+	s.Get("/v2", func(ctx context.Context) (interface{}, error) {
+		return nil, foundationerrorv2.New(codes.PermissionDenied).AppendMessage("admin only")
+	})
+	s.Get("/generic", func(ctx context.Context) (interface{}, error) {
+		return nil, fmt.Errorf("connection refused")
+	})
+
+	t.Run("errors.Error", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "2", w.Header().Get("Retry-After"))
+		var response ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 400, response.Code)
+		assert.Equal(t, "INVALID_REGISTRATION", response.Reason)
+		assert.Equal(t, "invalid registration", response.Message)
+		assert.Equal(t, []string{"email"}, response.Details)
+		assert.Equal(t, []errors.FieldViolation{{Field: "email", Description: "must be an email"}}, response.FieldViolations)
+		assert.NotEmpty(t, response.RequestID)
+		assert.Equal(t, w.Header().Get(HeaderRequestIDKey), response.RequestID)
+	})
+
+	t.Run("foundationerrorv2.Error", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v2", nil))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		var response ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "PERMISSION_DENIED", response.Reason)
+		assert.Equal(t, "admin only", response.Message)
+	})
+
+	t.Run("generic error", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/generic", nil))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	})
+}
+
+func TestErrorEncoderPluggable(t *testing.T) {
+	var textEncoder = func(code int) ErrorEncoder {
+		return func(ctx context.Context, w http.ResponseWriter, err error) {
+			w.WriteHeader(code)
+			_, _ = w.Write([]byte(err.Error()))
+		}
+	}
+	var s = NewServer(WithErrorEncoder(textEncoder(http.StatusTeapot)))
+	var handler = func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New(errors.ErrorTypeNotfound, "not found")
+	}
+	s.Get("/server", handler)
+	s.Get("/route", handler, ErrorEncoderMiddleware(textEncoder(http.StatusGone)))
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/server", nil))
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.Equal(t, "not found", w.Body.String())
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/route", nil))
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Equal(t, "not found", w.Body.String())
+}
+
+func TestResponseEncoderSkipOnError(t *testing.T) {
+	var encoded bool
+	var handler = execute("Get", "/test",
+		func(ctx context.Context) (interface{}, error) {
+			return nil, errors.New(errors.ErrorTypeNotfound, "not found")
+		},
+		ResponseEncoderMiddleware(func(ctx context.Context, response interface{}) (int, []byte, error) {
+			encoded = true
+			return http.StatusOK, nil, nil
+		}),
+	)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	assert.False(t, encoded)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestErrorResponseDebug(t *testing.T) {
+	defer foundation.SetDebugPolicy(foundation.DevelopmentDebugPolicy)
+	var handler = func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New(errors.ErrorTypeInternal, "pq: connection refused").WithDebug("SELECT 1")
+	}
+
+	foundation.SetDebugPolicy(func(ctx context.Context) bool { return false })
+	w := httptest.NewRecorder()
+	execute("Get", "/test", handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	var response ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+	assert.NotContains(t, response.Message, "pq")
+	assert.Nil(t, response.Debug)
+
+	foundation.SetDebugPolicy(func(ctx context.Context) bool { return true })
+	w = httptest.NewRecorder()
+	execute("Get", "/test", handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	response = ErrorResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "pq: connection refused", response.Message)
+	assert.Equal(t, "SELECT 1", response.Debug)
+}
+
+func TestErrorResponseDebug_ClaimsFromMiddleware(t *testing.T) {
+	defer foundation.SetDebugPolicy(foundation.DevelopmentDebugPolicy)
+	foundation.SetDebugPolicy(foundation.ClaimDebugPolicy("role", "admin"))
+	var handler = func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New(errors.ErrorTypeInternal, "pq: connection refused")
+	}
+	var auth = func(role string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context) (interface{}, error) {
+				return next(foundation.AppendClaimsToContext(ctx, map[string]interface{}{"role": role}))
+			}
+		}
+	}
+
+	w := httptest.NewRecorder()
+	execute("Get", "/test", handler, auth("admin")).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	var response ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "pq: connection refused", response.Message)
+
+	w = httptest.NewRecorder()
+	execute("Get", "/test", handler, auth("user")).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	response = ErrorResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.NotContains(t, response.Message, "pq")
+}
@@ -6,26 +6,95 @@ package http
 
 import (
 	"context"
+	"github.com/gorilla/mux"
 	"github.com/octofoxio/foundation"
 	"net/http"
+	"time"
 )
 
+type executeConfig struct {
+	errorEncoder ErrorEncoder
+	accessLog    bool
+}
+
 func execute(method string, path string, h Handler, middleware ...Middleware) http.HandlerFunc {
-	return func(writer http.ResponseWriter, request *http.Request) {
-		ctx := context.Background()
-		ctx = foundation.NewContext(ctx)
+	return executeWithConfig(executeConfig{errorEncoder: JSONErrorEncoder, accessLog: true}, method, path, h, middleware...)
+}
+
+// executeWithConfig run handler in context of the request, error returned
+// from handler is written by error encoder unless response is already written
+func executeWithConfig(config executeConfig, method string, path string, h Handler, middleware ...Middleware) http.HandlerFunc {
+	return func(w http.ResponseWriter, request *http.Request) {
+		var start = time.Now()
+		writer := newResponseWriter(w)
+		request = withRequestID(writer, request)
+		ctx := foundation.NewContext(request.Context())
+		ctx = foundation.WithDebugDecision(ctx)
+		ctx = foundation.AppendRequestIDToContext(ctx, foundation.GetRequestIDFromContext(ctx))
 		ctx = context.WithValue(ctx, RequestContextKey, request)
 		ctx = context.WithValue(ctx, ResponseWriterContextKey, writer)
-		ctx = foundation.AppendLoggerToContext(ctx, foundation.GetLoggerFromContext(ctx).WithURL(method, path))
+		var routeMethod, routePath = method, path
+		if routeMethod == "" {
+			routeMethod = request.Method
+		}
+		if routePath == "" {
+			routePath = request.URL.Path
+		}
+		ctx = foundation.AppendLoggerToContext(ctx, foundation.GetLoggerFromContext(ctx).WithURL(routeMethod, routePath))
+		ctx = context.WithValue(ctx, PathVarsContextKey, mux.Vars(request))
+		if token := accessTokenFromHeader(request); token != "" {
+			ctx = context.WithValue(ctx, foundation.FoundationAccessTokenContextKey, token)
+		}
+		if locale := request.Header.Get(HeaderAcceptLanguageKey); locale != "" {
+			ctx = foundation.AppendLocaleToContext(ctx, locale)
+		}
+		if request.TLS != nil && len(request.TLS.VerifiedChains) > 0 && len(request.TLS.VerifiedChains[0]) > 0 {
+			ctx = foundation.AppendClientCertificateToContext(ctx, request.TLS.VerifiedChains[0][0])
+		}
 
-		var handler = h
+		// debug decision is made with context of handler,
+		// so claims added by middleware are used by error encoder
+		var handler Handler = func(ctx context.Context) (interface{}, error) {
+			output, err := h(ctx)
+			if err != nil {
+				foundation.RecordDebugDecision(ctx)
+			}
+			return output, err
+		}
 		for _, m := range middleware {
 			handler = m(handler)
 		}
 		_, err := handler(ctx)
 		if err != nil {
-			panic(err)
+			encodeError(ctx, config.errorEncoder, err)
 		}
+		if config.accessLog {
+			writeAccessLog(ctx, request, writer, time.Since(start), err)
+		}
+	}
+}
 
+// writeAccessLog log a line of request with status and size of response,
+// server errors are logged as error and the others as info
+func writeAccessLog(ctx context.Context, r *http.Request, w *responseWriter, duration time.Duration, err error) {
+	var status = w.Status()
+	if status == 0 {
+		// nothing is written, net/http send 200
+		status = http.StatusOK
+	}
+	log := foundation.GetLoggerFromContext(ctx).
+		WithField("status", status).
+		WithField("size", w.size).
+		WithField("duration", duration.Round(time.Microsecond).String()).
+		WithField("uri", r.URL.RequestURI()).
+		WithField("remote", r.RemoteAddr).
+		WithField("user-agent", r.UserAgent())
+	if err != nil {
+		log = log.WithError(err)
+	}
+	if status >= http.StatusInternalServerError {
+		log.Error("access")
+	} else {
+		log.Info("access")
 	}
 }
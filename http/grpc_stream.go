@@ -0,0 +1,194 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/octofoxio/foundation/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// GRPCMetadataLastEventIDKey is metadata which carry Last-Event-ID
+// of reconnecting client to the streaming method
+const GRPCMetadataLastEventIDKey = "last-event-id"
+
+// GRPCStreamHandler expose server streaming method of gRPC service behind
+// conn as SSE, or NDJSON when client accept application/x-ndjson.
+// Request is decoded the same way as Handle, event ID is sequence of messages
+// which continue from numeric Last-Event-ID, so the method can skip messages
+// that client already received. Error before the first message is normal
+// error response, after that it is sent as "error" event (or error line)
+//
+// ```
+// s.Get("/v1/orders/{id}/events", http.GRPCStreamHandler[*app.WatchInput, *app.OrderEvent](conn, "/app.Orders/Watch", http.SSEOptions{}))
+// ```
+func GRPCStreamHandler[In proto.Message, Out proto.Message](conn grpc.ClientConnInterface, fullMethod string, options SSEOptions) Handler {
+	var (
+		decode = defaultDecoder[In]()
+		desc   = &grpc.StreamDesc{
+			StreamName:    fullMethod[strings.LastIndex(fullMethod, "/")+1:],
+			ServerStreams: true,
+		}
+	)
+	return func(ctx context.Context) (interface{}, error) {
+		input, err := decode(ctx, getRequestFromContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		var sequence int64
+		if lastEventID := LastEventID(ctx); lastEventID != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, GRPCMetadataLastEventIDKey, lastEventID)
+			sequence, _ = strconv.ParseInt(lastEventID, 10, 64)
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		stream, err := conn.NewStream(ctx, desc, fullMethod)
+		if err != nil {
+			return nil, errors.From(err)
+		}
+		if err := stream.SendMsg(input); err != nil {
+			return nil, errors.From(err)
+		}
+		if err := stream.CloseSend(); err != nil {
+			return nil, errors.From(err)
+		}
+		var recv = func() (Out, error) {
+			var out = newMessage[Out]()
+			err := stream.RecvMsg(out)
+			return out, err
+		}
+		// error of the call is received with the first message
+		first, firstErr := recv()
+		if firstErr != nil && firstErr != io.EOF {
+			return nil, errors.From(firstErr)
+		}
+		var buffered = true
+		var next = func() (Out, error) {
+			if buffered {
+				buffered = false
+				return first, firstErr
+			}
+			return recv()
+		}
+
+		if strings.Contains(getRequestFromContext(ctx).Header.Get(HeaderAcceptKey), ContentTypeNDJSON) {
+			return NDJSONHandler(func(ctx context.Context, send func(item Out) error) error {
+				for {
+					message, err := next()
+					if err == io.EOF {
+						return nil
+					}
+					if err != nil {
+						return errors.From(err)
+					}
+					if err := send(message); err != nil {
+						return err
+					}
+				}
+			})(ctx)
+		}
+
+		var events = make(chan Event)
+		go func() {
+			defer close(events)
+			for {
+				message, err := next()
+				if err == io.EOF {
+					return
+				}
+				var event Event
+				if err != nil {
+					event = Event{Event: "error", Data: NewErrorResponse(ctx, errors.From(err))}
+				} else {
+					sequence++
+					event = Event{ID: strconv.FormatInt(sequence, 10), Data: message}
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+		return nil, ServeSSE(ctx, events, options)
+	}
+}
+
+// newMessage create message of pointer type T (e.g. *app.PingInput)
+func newMessage[T proto.Message]() T {
+	return reflect.New(reflect.TypeOf((*T)(nil)).Elem().Elem()).Interface().(T)
+}
+
+// GRPCBidiStreamHandler bridge WebSocket connection to bidirectional streaming
+// method of gRPC service behind conn, each message from client is sent to the
+// stream and each message from the stream is written to client. Client closing
+// the connection close send direction, the connection is closed when the stream end
+//
+// ```
+// s.WebSocket("/chat", http.GRPCBidiStreamHandler[*app.ChatMessage, *app.ChatEvent](conn, "/app.Chat/Connect"), http.WebSocketOptions{})
+// ```
+func GRPCBidiStreamHandler[In proto.Message, Out proto.Message](conn grpc.ClientConnInterface, fullMethod string) WebSocketHandler {
+	var desc = &grpc.StreamDesc{
+		StreamName:    fullMethod[strings.LastIndex(fullMethod, "/")+1:],
+		ServerStreams: true,
+		ClientStreams: true,
+	}
+	return func(ctx context.Context, ws *WebSocketConn) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stream, err := conn.NewStream(ctx, desc, fullMethod)
+		if err != nil {
+			return errors.From(err)
+		}
+
+		var readErr = make(chan error, 1)
+		go func() {
+			for {
+				var input = newMessage[In]()
+				if err := ws.Read(input); err != nil {
+					if err == io.EOF {
+						_ = stream.CloseSend()
+					} else {
+						readErr <- err
+						cancel()
+					}
+					return
+				}
+				if err := stream.SendMsg(input); err != nil {
+					// error of the stream is received by RecvMsg
+					return
+				}
+			}
+		}()
+
+		for {
+			var output = newMessage[Out]()
+			if err := stream.RecvMsg(output); err != nil {
+				select {
+				case err := <-readErr:
+					return err
+				default:
+				}
+				if err == io.EOF || ctx.Err() != nil {
+					return nil
+				}
+				return errors.From(err)
+			}
+			if err := ws.Write(output); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/octofoxio/foundation"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// watchStream send PingOutput three times, continue from last-event-id
+func watchStream(srv interface{}, stream grpc.ServerStream) error {
+	var input foundation.PingInput
+	if err := stream.RecvMsg(&input); err != nil {
+		return err
+	}
+	if input.Greeting == "" {
+		return errors.New(errors.ErrorTypeBadInput, "greeting is required")
+	}
+	var from = 0
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok && len(md.Get(GRPCMetadataLastEventIDKey)) > 0 {
+		from, _ = strconv.Atoi(md.Get(GRPCMetadataLastEventIDKey)[0])
+	}
+	for i := from + 1; i <= 3; i++ {
+		if err := stream.SendMsg(&foundation.PingOutput{Greeting: fmt.Sprintf("%s %d", input.Greeting, i)}); err != nil {
+			return err
+		}
+	}
+	if input.Greeting == "fail" {
+		return errors.New(errors.ErrorTypeUnavailable, "stream is broken")
+	}
+	return nil
+}
+
+// echoStream reply every PingInput until client close send
+func echoStream(srv interface{}, stream grpc.ServerStream) error {
+	for {
+		var input foundation.PingInput
+		if err := stream.RecvMsg(&input); err == io.EOF {
+			return stream.SendMsg(&foundation.PingOutput{Greeting: "bye"})
+		} else if err != nil {
+			return err
+		}
+		if input.Greeting == "fail" {
+			return errors.New(errors.ErrorTypeForbidden, "not allowed")
+		}
+		if err := stream.SendMsg(&foundation.PingOutput{Greeting: "echo " + input.Greeting}); err != nil {
+			return err
+		}
+	}
+}
+
+func newStreamConn(t *testing.T) *grpc.ClientConn {
+	var lis = bufconn.Listen(1 << 20)
+	var server = grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "grpc.Watcher",
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Watch",
+			Handler:       watchStream,
+			ServerStreams: true,
+		}, {
+			StreamName:    "Echo",
+			Handler:       echoStream,
+			ServerStreams: true,
+			ClientStreams: true,
+		}},
+	}, struct{}{})
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+	conn, err := grpc.Dial("bufconn", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	assert.NoError(t, err)
+	return conn
+}
+
+func TestGRPCStreamHandler(t *testing.T) {
+	var s = NewServer()
+	s.Get("/watch", GRPCStreamHandler[*foundation.PingInput, *foundation.PingOutput](newStreamConn(t), "/grpc.Watcher/Watch", SSEOptions{}))
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/watch?greeting=hi", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "id: 1\ndata: {\"greeting\":\"hi 1\"}\n\nid: 2\ndata: {\"greeting\":\"hi 2\"}\n\nid: 3\ndata: {\"greeting\":\"hi 3\"}\n\n", w.Body.String())
+
+	r := httptest.NewRequest(http.MethodGet, "/watch?greeting=hi", nil)
+	r.Header.Set(HeaderLastEventIDKey, "2")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, "id: 3\ndata: {\"greeting\":\"hi 3\"}\n\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/watch?greeting=fail", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "event: error\ndata: {\"code\":503")
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/watch", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "greeting is required")
+
+	r = httptest.NewRequest(http.MethodGet, "/watch?greeting=hi", nil)
+	r.Header.Set(HeaderAcceptKey, ContentTypeNDJSON)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, ContentTypeNDJSON, w.Header().Get(HeaderContentTypeKey))
+	assert.Len(t, strings.Split(strings.TrimSpace(w.Body.String()), "\n"), 3)
+}
+
+func TestGRPCBidiStreamHandler(t *testing.T) {
+	var s = NewServer()
+	s.WebSocket("/echo", GRPCBidiStreamHandler[*foundation.PingInput, *foundation.PingOutput](newStreamConn(t), "/grpc.Watcher/Echo"), WebSocketOptions{})
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	conn, _, err := dialWebSocket(t, server, "/echo", nil)
+	assert.NoError(t, err)
+	var output map[string]string
+	assert.NoError(t, conn.WriteJSON(map[string]string{"greeting": "hi"}))
+	assert.NoError(t, conn.ReadJSON(&output))
+	assert.Equal(t, "echo hi", output["greeting"])
+
+	// client close send direction, server still reply before the stream end
+	assert.NoError(t, conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")))
+	assert.NoError(t, conn.ReadJSON(&output))
+	assert.Equal(t, "bye", output["greeting"])
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), err)
+
+	conn, _, err = dialWebSocket(t, server, "/echo", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, conn.WriteJSON(map[string]string{"greeting": "fail"}))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, 4403), err)
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"github.com/octofoxio/foundation/errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrRequestTimeout = errors.Define("REQUEST_TIMEOUT", errors.ErrorTypeTimeout, "request is not completed in {timeout}", "Handler take longer than timeout of the route")
+
+// BodyLimitMiddleware reject request which body is larger than limit,
+// request with larger Content-Length is rejected before handler is called
+// and the others fail with ErrRequestTooLarge when handler read over limit
+func BodyLimitMiddleware(limit int64) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context) (interface{}, error) {
+			var r = getRequestFromContext(ctx)
+			if r.ContentLength > limit {
+				return nil, ErrRequestTooLarge.New(errors.Params{"limit": limit})
+			}
+			if r.Body == nil || r.Body == http.NoBody {
+				return next(ctx)
+			}
+			req := r.Clone(r.Context())
+			req.Body = &limitedBody{ReadCloser: r.Body, remaining: limit, limit: limit}
+			return next(withRequest(ctx, req))
+		}
+	}
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	limit     int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, ErrRequestTooLarge.New(errors.Params{"limit": b.limit})
+	}
+	// read one more byte to know that body is over limit
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) <= b.remaining {
+		b.remaining -= int64(n)
+		return n, err
+	}
+	n, b.remaining, b.exceeded = int(b.remaining), 0, true
+	return n, ErrRequestTooLarge.New(errors.Params{"limit": b.limit})
+}
+
+// TimeoutMiddleware cancel context of handler after timeout and
+// return ErrRequestTimeout, response is buffered until handler is
+// completed so it is not suitable for streaming routes
+func TimeoutMiddleware(timeout time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context) (interface{}, error) {
+			var r = getRequestFromContext(ctx)
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			var (
+				w    = getResponseWriterFromContext(ctx)
+				tw   = &timeoutWriter{header: w.Header().Clone()}
+				done = make(chan struct{})
+
+				output   interface{}
+				err      error
+				panicked interface{}
+			)
+			go func() {
+				defer close(done)
+				defer func() {
+					panicked = recover()
+				}()
+				output, err = next(withResponseWriter(withRequest(ctx, r.WithContext(ctx)), tw))
+			}()
+
+			select {
+			case <-done:
+				if panicked != nil {
+					// let RecoveryMiddleware handle it
+					panic(panicked)
+				}
+				tw.flush(w)
+				return output, err
+			case <-ctx.Done():
+				tw.timeout()
+				if ctx.Err() == context.DeadlineExceeded {
+					return nil, ErrRequestTimeout.New(errors.Params{"timeout": timeout})
+				}
+				return nil, ctx.Err()
+			}
+		}
+	}
+}
+
+// timeoutWriter buffer response of handler, writes after
+// timeout fail with http.ErrHandlerTimeout
+type timeoutWriter struct {
+	mux      sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if w.status == 0 && !w.timedOut {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.buf.Write(b)
+}
+
+func (w *timeoutWriter) timeout() {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.timedOut = true
+}
+
+func (w *timeoutWriter) flush(dst http.ResponseWriter) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	for key, values := range w.header {
+		dst.Header()[key] = values
+	}
+	if w.status == 0 {
+		// nothing is written, e.g. handler return error
+		return
+	}
+	dst.WriteHeader(w.status)
+	_, _ = dst.Write(w.buf.Bytes())
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	var s = NewServer()
+	HandleFunc(s, http.MethodPost, "/greet", greeter{}.Greet, BodyLimitMiddleware(20))
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/greet", strings.NewReader(`{"name": "john"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/greet", strings.NewReader(`{"name": "john doe the third"}`)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), ErrRequestTooLarge.Reason)
+
+	// unknown length
+	r := httptest.NewRequest(http.MethodPost, "/greet", strings.NewReader(`{"name": "john doe the third"}`))
+	r.ContentLength = -1
+	r.Header.Set(HeaderContentTypeKey, ContentTypeJSON)
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), ErrRequestTooLarge.Reason)
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	var s = NewServer()
+	s.Get("/slow", func(ctx context.Context) (interface{}, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+		}
+		return nil, writeResponse(ctx, http.StatusOK, []byte("late"))
+	}, TimeoutMiddleware(10*time.Millisecond))
+	s.Get("/fast", func(ctx context.Context) (interface{}, error) {
+		_, ok := getRequestFromContext(ctx).Context().Deadline()
+		assert.True(t, ok, "request has the same deadline")
+		getResponseWriterFromContext(ctx).Header().Set("X-Fast", "1")
+		return nil, writeResponse(ctx, http.StatusCreated, []byte("ok"))
+	}, TimeoutMiddleware(time.Second))
+	s.Get("/panic", func(ctx context.Context) (interface{}, error) {
+		panic("boom")
+	}, TimeoutMiddleware(time.Second))
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, errors.ErrorTypeTimeout.HTTPStatus(), w.Code)
+	assert.Contains(t, w.Body.String(), ErrRequestTimeout.Reason)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-Fast"))
+	assert.Equal(t, "ok", w.Body.String())
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -7,6 +7,7 @@ package http
 import (
 	"context"
 	"github.com/octofoxio/foundation"
+	"github.com/octofoxio/foundation/errors"
 	"net/http"
 )
 
@@ -34,17 +35,74 @@ func ResponseEncoderMiddleware(encoder ResponseEncoder) Middleware {
 	return func(next Handler) Handler {
 		return func(ctx context.Context) (i interface{}, e error) {
 			output, err := next(ctx)
-			w := getResponseWriterFromContext(ctx)
-			code, body, err := encoder(ctx, output)
 			if err != nil {
 				return nil, err
 			}
-			w.WriteHeader(code)
-			_, err = w.Write(body)
+			code, body, err := encoder(ctx, output)
 			if err != nil {
 				return nil, err
 			}
+			if err := writeResponse(ctx, code, body); err != nil {
+				return nil, err
+			}
+			return output, nil
+		}
+	}
+}
+
+func writeResponse(ctx context.Context, code int, body []byte) error {
+	w := getResponseWriterFromContext(ctx)
+	w.WriteHeader(code)
+	_, err := w.Write(body)
+	return err
+}
+
+// LocalizeErrorMiddleware render message of returned error
+// in client locale (from Accept-Language header) as LocalizedMessage
+func LocalizeErrorMiddleware(localizer *errors.Localizer) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context) (i interface{}, e error) {
+			output, err := next(ctx)
+			if err != nil {
+				err = localizer.Localize(err, foundation.GetLocaleFromContext(ctx))
+			}
 			return output, err
 		}
 	}
 }
+
+// ForwardAuthorizationMiddleware append access token of the request
+// to outgoing gRPC metadata, so gRPC services called by handler receive it.
+// Token is not forwarded by default, use it only for routes which call
+// trusted services
+//
+// ```
+// s := http.NewServer(http.WithMiddleware(http.ForwardAuthorizationMiddleware()))
+// ```
+func ForwardAuthorizationMiddleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context) (interface{}, error) {
+			if token := foundation.GetAccessTokenFromContext(ctx); token != "" {
+				ctx = foundation.AppendAuthorizationToContext(ctx, token)
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// HTTPMiddleware adapt foundation Middleware to net/http middleware,
+// so it can wrap any http.Handler (e.g. the whole Server or a file server).
+// Error returned from middleware is written by JSONErrorEncoder,
+// access log is not written since the Server write its own
+//
+// ```
+// http2.ListenAndServe(":3009", http.HTTPMiddleware(http.CORSMiddleware(options))(httpServer))
+// ```
+func HTTPMiddleware(m Middleware) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return executeWithConfig(executeConfig{errorEncoder: JSONErrorEncoder}, "", "", m(func(ctx context.Context) (interface{}, error) {
+			next.ServeHTTP(getResponseWriterFromContext(ctx), getRequestFromContext(ctx))
+			return nil, nil
+		}))
+	}
+}
@@ -0,0 +1,474 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/mux"
+	"github.com/octofoxio/foundation/errors"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const openAPIVersion = "3.0.3"
+
+// RouteDoc is optional metadata of route which is used to
+// generate OpenAPI document, Request and Response are
+// values (or pointers) of request and response types
+//
+// ```
+// s.Document(s.Get("/users/{id}", getUser), http.RouteDoc{
+// Summary:  "Get user by ID",
+// Response: &User{},
+// Errors:   []*errors.Definition{ErrUserNotFound},
+// })
+// ```
+type RouteDoc struct {
+	OperationID string
+	Summary     string
+	Description string
+	Tags        []string
+	Request     interface{}
+	Response    interface{}
+	Errors      []*errors.Definition
+	Deprecated  bool
+	Hidden      bool
+}
+
+// routeDocs is shared by server and its groups
+type routeDocs struct {
+	mux  *sync.RWMutex
+	docs map[*mux.Route]RouteDoc
+}
+
+func newRouteDocs() *routeDocs {
+	return &routeDocs{
+		mux:  &sync.RWMutex{},
+		docs: map[*mux.Route]RouteDoc{},
+	}
+}
+
+func (d *routeDocs) get(route *mux.Route) (RouteDoc, bool) {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	doc, ok := d.docs[route]
+	return doc, ok
+}
+
+// Document attach metadata to route, routes without
+// metadata are still in the document with their path and methods
+func (s *Server) Document(route *mux.Route, doc RouteDoc) *mux.Route {
+	s.docs.mux.Lock()
+	defer s.docs.mux.Unlock()
+	s.docs.docs[route] = doc
+	return route
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type OpenAPIServer struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+type OpenAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Servers    []OpenAPIServer                         `json:"servers,omitempty"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                       `json:"components"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*OpenAPISchema `json:"schemas,omitempty"`
+}
+
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+}
+
+type OpenAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required,omitempty"`
+	Schema   *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                         `json:"required,omitempty"`
+	Content  map[string]*OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+// OpenAPI generate OpenAPI 3 document from every routes of
+// the server (including groups), schemas are generated by reflection
+// on Request and Response of RouteDoc, proto messages use their jsonpb names
+func (s *Server) OpenAPI(info OpenAPIInfo) *OpenAPIDocument {
+	var (
+		schemas = newSchemaGenerator()
+		doc     = &OpenAPIDocument{
+			OpenAPI: openAPIVersion,
+			Info:    info,
+			Paths:   map[string]map[string]*OpenAPIOperation{},
+		}
+	)
+	_ = s.root.r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil || route.GetHandler() == nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		routeDoc, _ := s.docs.get(route)
+		if routeDoc.Hidden {
+			return nil
+		}
+		path, pathParams := openAPIPath(template)
+		for _, method := range methods {
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = map[string]*OpenAPIOperation{}
+			}
+			doc.Paths[path][strings.ToLower(method)] = newOperation(schemas, method, pathParams, routeDoc)
+		}
+		return nil
+	})
+	doc.Components.Schemas = schemas.schemas
+	return doc
+}
+
+func newOperation(schemas *schemaGenerator, method string, pathParams []string, doc RouteDoc) *OpenAPIOperation {
+	var operation = &OpenAPIOperation{
+		OperationID: doc.OperationID,
+		Summary:     doc.Summary,
+		Description: doc.Description,
+		Tags:        doc.Tags,
+		Deprecated:  doc.Deprecated,
+		Responses:   map[string]*OpenAPIResponse{},
+	}
+
+	var (
+		properties = map[string]*OpenAPISchema{}
+		request    *OpenAPISchema
+	)
+	if doc.Request != nil {
+		request = schemas.schema(reflect.TypeOf(doc.Request), false)
+		properties = schemas.properties(request)
+	}
+	var isPathParam = map[string]bool{}
+	for _, name := range pathParams {
+		isPathParam[name] = true
+		schema, ok := properties[name]
+		if !ok {
+			schema = &OpenAPISchema{Type: "string"}
+		}
+		operation.Parameters = append(operation.Parameters, &OpenAPIParameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	switch {
+	case request == nil:
+	case method == http.MethodGet || method == http.MethodHead || method == http.MethodDelete:
+		var names []string
+		for name, schema := range properties {
+			if !isPathParam[name] && schema.Ref == "" && schema.Type != "object" {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			operation.Parameters = append(operation.Parameters, &OpenAPIParameter{Name: name, In: "query", Schema: properties[name]})
+		}
+	default:
+		operation.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content:  map[string]*OpenAPIMediaType{ContentTypeJSON: {Schema: request}},
+		}
+	}
+
+	var success = &OpenAPIResponse{Description: http.StatusText(http.StatusOK)}
+	if doc.Response != nil {
+		success.Content = map[string]*OpenAPIMediaType{
+			ContentTypeJSON: {Schema: schemas.schema(reflect.TypeOf(doc.Response), false)},
+		}
+	}
+	operation.Responses[strconv.Itoa(http.StatusOK)] = success
+
+	var errorContent = map[string]*OpenAPIMediaType{
+		ContentTypeJSON: {Schema: schemas.schema(reflect.TypeOf(ErrorResponse{}), false)},
+	}
+	for _, d := range doc.Errors {
+		code := strconv.Itoa(d.Type.HTTPStatus())
+		response, ok := operation.Responses[code]
+		if !ok {
+			response = &OpenAPIResponse{Content: errorContent}
+			operation.Responses[code] = response
+		} else {
+			response.Description += "\n\n"
+		}
+		response.Description += fmt.Sprintf("%s: %s", d.Reason, d.Doc)
+	}
+	operation.Responses["default"] = &OpenAPIResponse{Description: "Error", Content: errorContent}
+	return operation
+}
+
+// openAPIPath convert mux path template to OpenAPI path,
+// patterns of variables are removed (e.g. "/users/{id:[0-9]+}" to "/users/{id}")
+func openAPIPath(template string) (string, []string) {
+	var (
+		b      strings.Builder
+		params []string
+		depth  int
+		name   strings.Builder
+		inName bool
+	)
+	for _, c := range template {
+		switch {
+		case c == '{':
+			depth++
+			if depth == 1 {
+				inName = true
+				name.Reset()
+				continue
+			}
+		case c == '}':
+			depth--
+			if depth == 0 {
+				params = append(params, name.String())
+				b.WriteString("{" + name.String() + "}")
+				inName = false
+				continue
+			}
+		case c == ':' && depth == 1:
+			inName = false
+		}
+		if depth == 0 {
+			b.WriteRune(c)
+		} else if inName {
+			name.WriteRune(c)
+		}
+	}
+	return b.String(), params
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	protoMessageType  = reflect.TypeOf((*proto.Message)(nil)).Elem()
+	invalidSchemaName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// schemaGenerator generate schema of Go types, named structs
+// are added to components and referenced by $ref
+type schemaGenerator struct {
+	schemas map[string]*OpenAPISchema
+	names   map[reflect.Type]string
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		schemas: map[string]*OpenAPISchema{},
+		names:   map[reflect.Type]string{},
+	}
+}
+
+// properties return properties of schema, $ref is resolved
+func (g *schemaGenerator) properties(schema *OpenAPISchema) map[string]*OpenAPISchema {
+	if schema.Ref != "" {
+		if resolved, ok := g.schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]; ok {
+			return resolved.Properties
+		}
+	}
+	return schema.Properties
+}
+
+func (g *schemaGenerator) schema(t reflect.Type, isProto bool) *OpenAPISchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		if isProto {
+			// jsonpb write 64 bit integers as string
+			return &OpenAPISchema{Type: "string", Format: "int64"}
+		}
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &OpenAPISchema{Type: "string", Format: "byte"}
+		}
+		return &OpenAPISchema{Type: "array", Items: g.schema(t.Elem(), isProto)}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: g.schema(t.Elem(), isProto)}
+	case reflect.Struct:
+		return g.structSchema(t)
+	}
+	return &OpenAPISchema{}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *OpenAPISchema {
+	var isProto = reflect.PtrTo(t).Implements(protoMessageType)
+	if t.Name() == "" {
+		return g.objectSchema(t, isProto)
+	}
+	name, ok := g.names[t]
+	if !ok {
+		name = g.schemaName(t, isProto)
+		g.names[t] = name
+		// placeholder for recursive types
+		g.schemas[name] = &OpenAPISchema{Type: "object"}
+		g.schemas[name] = g.objectSchema(t, isProto)
+	}
+	return &OpenAPISchema{Ref: "#/components/schemas/" + name}
+}
+
+func (g *schemaGenerator) schemaName(t reflect.Type, isProto bool) string {
+	var name = t.Name()
+	if isProto {
+		name = proto.MessageName(reflect.New(t).Interface().(proto.Message))
+	}
+	name = invalidSchemaName.ReplaceAllString(name, "_")
+	for _, exists := g.schemas[name]; exists; _, exists = g.schemas[name] {
+		// same name from different packages
+		name = invalidSchemaName.ReplaceAllString(t.PkgPath(), "_") + "." + name
+	}
+	return name
+}
+
+func (g *schemaGenerator) objectSchema(t reflect.Type, isProto bool) *OpenAPISchema {
+	var schema = &OpenAPISchema{Type: "object", Properties: map[string]*OpenAPISchema{}}
+	g.addProperties(schema, t, isProto)
+	return schema
+}
+
+func (g *schemaGenerator) addProperties(schema *OpenAPISchema, t reflect.Type, isProto bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if strings.HasPrefix(f.Name, "XXX_") || f.Tag.Get("protobuf_oneof") != "" {
+			continue
+		}
+		jsonName := strings.Split(f.Tag.Get("json"), ",")[0]
+		if jsonName == "-" {
+			continue
+		}
+		if f.Anonymous && jsonName == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addProperties(schema, embedded, isProto)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		name, enum := protoFieldName(f)
+		if name == "" {
+			name = fieldName(f, "json")
+		}
+		if enum != "" {
+			schema.Properties[name] = enumSchema(enum)
+			continue
+		}
+		schema.Properties[name] = g.schema(f.Type, isProto)
+	}
+}
+
+// protoFieldName return jsonpb name and enum type of field from protobuf tag
+func protoFieldName(f reflect.StructField) (name string, enum string) {
+	for _, option := range strings.Split(f.Tag.Get("protobuf"), ",") {
+		switch {
+		case strings.HasPrefix(option, "name=") && name == "":
+			name = strings.TrimPrefix(option, "name=")
+		case strings.HasPrefix(option, "json="):
+			name = strings.TrimPrefix(option, "json=")
+		case strings.HasPrefix(option, "enum="):
+			enum = strings.TrimPrefix(option, "enum=")
+		}
+	}
+	return name, enum
+}
+
+// enumSchema list enum names ordered by their number, jsonpb write enums as names
+func enumSchema(enum string) *OpenAPISchema {
+	var values = proto.EnumValueMap(enum)
+	var names = make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return values[names[i]] < values[names[j]]
+	})
+	return &OpenAPISchema{Type: "string", Enum: names}
+}
+
+// ServeOpenAPI serve OpenAPI document of the server at path, the document
+// is generated on request so routes which registered later are included
+//
+// ```
+// s.ServeOpenAPI("/openapi.json", http.OpenAPIInfo{Title: "stringsvc", Version: "1.0.0"})
+// ```
+func (s *Server) ServeOpenAPI(path string, info OpenAPIInfo) *mux.Route {
+	return s.Document(s.Get(path, func(ctx context.Context) (interface{}, error) {
+		body, err := json.Marshal(s.OpenAPI(info))
+		if err != nil {
+			return nil, err
+		}
+		getResponseWriterFromContext(ctx).Header().Set(HeaderContentTypeKey, ContentTypeJSON)
+		return nil, writeResponse(ctx, http.StatusOK, body)
+	}), RouteDoc{Hidden: true})
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/octofoxio/foundation"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+var errDocTestNotFound = errors.Define("DOC_TEST_NOT_FOUND", errors.ErrorTypeNotfound, "user {id} not found", "Returned when the user does not exist")
+
+type docAddress struct {
+	City string `json:"city"`
+}
+
+type docUser struct {
+	ID        int64             `json:"id"`
+	Name      string            `json:"name"`
+	Tags      []string          `json:"tags,omitempty"`
+	Labels    map[string]string `json:"labels"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Friends   []*docUser        `json:"friends"`
+	Secret    string            `json:"-"`
+	docAddress
+}
+
+func TestServer_OpenAPI(t *testing.T) {
+	var s = NewServer()
+	var noop = func(ctx context.Context) (interface{}, error) { return nil, nil }
+	s.Document(s.Post("/users/{id:[0-9]+}", noop), RouteDoc{
+		OperationID: "updateUser",
+		Summary:     "Update user",
+		Tags:        []string{"user"},
+		Request:     &docUser{},
+		Response:    docUser{},
+		Errors:      []*errors.Definition{errDocTestNotFound, ErrInvalidRequestBody},
+	})
+	s.Group("/v1").Get("/health", noop)
+	s.Any("/any", noop)
+	HandleFunc(s, http.MethodGet, "/ping/{greeting}", pingService{}.Ping)
+	assert.NoError(t, RegisterGRPCService(s, "grpc.Test", pingService{}))
+	s.ServeOpenAPI("/openapi.json", OpenAPIInfo{Title: "test", Version: "1.0.0"})
+
+	doc := s.OpenAPI(OpenAPIInfo{Title: "test", Version: "1.0.0"})
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Len(t, doc.Paths, 4, "hidden and any routes are not included")
+	assert.Contains(t, doc.Paths["/v1/health"], "get")
+
+	update := doc.Paths["/users/{id}"]["post"]
+	assert.Equal(t, "updateUser", update.OperationID)
+	assert.Equal(t, []*OpenAPIParameter{{Name: "id", In: "path", Required: true, Schema: &OpenAPISchema{Type: "integer", Format: "int64"}}}, update.Parameters)
+	assert.Equal(t, "#/components/schemas/docUser", update.RequestBody.Content[ContentTypeJSON].Schema.Ref)
+	assert.Equal(t, "DOC_TEST_NOT_FOUND: Returned when the user does not exist", update.Responses["404"].Description)
+	assert.Contains(t, update.Responses, "400")
+	assert.Contains(t, update.Responses, "default")
+	assert.Equal(t, "#/components/schemas/ErrorResponse", update.Responses["404"].Content[ContentTypeJSON].Schema.Ref)
+
+	user := doc.Components.Schemas["docUser"]
+	assert.Equal(t, []string{"city", "createdAt", "friends", "id", "labels", "name", "tags"}, keys(user.Properties))
+	assert.Equal(t, &OpenAPISchema{Type: "string", Format: "date-time"}, user.Properties["createdAt"])
+	assert.Equal(t, "#/components/schemas/docUser", user.Properties["friends"].Items.Ref)
+	assert.Equal(t, &OpenAPISchema{Type: "string"}, user.Properties["labels"].AdditionalProperties)
+
+	ping := doc.Paths["/ping/{greeting}"]["get"]
+	assert.Len(t, ping.Parameters, 1, "path param is not repeated in query")
+	assert.Equal(t, "#/components/schemas/grpc.PingOutput", ping.Responses["200"].Content[ContentTypeJSON].Schema.Ref)
+
+	transcoded := doc.Paths["/grpc.Test/Ping"]["post"]
+	assert.Equal(t, "Test_Ping", transcoded.OperationID)
+	assert.Equal(t, []string{"grpc.Test"}, transcoded.Tags)
+	assert.Equal(t, "#/components/schemas/grpc.PingInput", transcoded.RequestBody.Content[ContentTypeJSON].Schema.Ref)
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ContentTypeJSON, w.Header().Get(HeaderContentTypeKey))
+	var served OpenAPIDocument
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &served))
+	assert.Equal(t, "test", served.Info.Title)
+	assert.Len(t, served.Paths, 4)
+}
+
+func TestOpenAPIPath(t *testing.T) {
+	path, params := openAPIPath("/v1/{name:shelves/[^/]+}/books/{id:[0-9]{2}}")
+	assert.Equal(t, "/v1/{name}/books/{id}", path)
+	assert.Equal(t, []string{"name", "id"}, params)
+}
+
+func TestSchemaGenerator_Proto(t *testing.T) {
+	g := newSchemaGenerator()
+	assert.Equal(t, "#/components/schemas/grpc.PingInput", g.schema(reflect.TypeOf(&foundation.PingInput{}), false).Ref)
+	assert.Equal(t, map[string]*OpenAPISchema{"greeting": {Type: "string"}}, g.schemas["grpc.PingInput"].Properties)
+	assert.Equal(t, &OpenAPISchema{Type: "string", Format: "int64"}, g.schema(reflect.TypeOf(int64(0)), true))
+}
+
+func keys(m map[string]*OpenAPISchema) []string {
+	var result []string
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,27 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"github.com/octofoxio/foundation"
+	"github.com/octofoxio/foundation/logger"
+)
+
+// RecoveryMiddleware convert panic in handler (and middleware before it)
+// to returned error, Server add it to every routes as the outer most middleware
+func RecoveryMiddleware(reporters ...logger.Reporter) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context) (i interface{}, e error) {
+			defer func() {
+				if r := recover(); r != nil {
+					i = nil
+					e = foundation.RecoverPanic(ctx, r, reporters...)
+				}
+			}()
+			return next(ctx)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/octofoxio/foundation/logger"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type recordReporter struct {
+	events []*logger.Event
+}
+
+func (r *recordReporter) Report(event *logger.Event) {
+	r.events = append(r.events, event)
+}
+
+func TestRecovery(t *testing.T) {
+	var reporter = &recordReporter{}
+	var s = NewServer(WithReporter(reporter))
+	s.Get("/panic", func(ctx context.Context) (interface{}, error) {
+		panic("boom")
+	})
+	s.Get("/error", func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New(errors.ErrorTypeNotfound, "not found")
+	})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.NotContains(t, body["message"], "boom", "internal message must not be sent outside development")
+	assert.Len(t, reporter.events, 1)
+	assert.Contains(t, reporter.events[0].Method, "/panic")
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/error", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Len(t, reporter.events, 1)
+}
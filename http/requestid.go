@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"github.com/octofoxio/foundation"
+	"github.com/rs/xid"
+	"net/http"
+	"strings"
+)
+
+const (
+	HeaderXRequestIDKey    = "X-Request-ID"
+	HeaderAmznRequestIDKey = "X-Amzn-RequestId"
+	HeaderTraceParentKey   = "traceparent"
+
+	maxRequestIDLength = 128
+)
+
+// incomingRequestID read request ID which is sent by client or proxy from
+// RequestID, X-Request-ID, X-Amzn-RequestId or trace ID of W3C traceparent,
+// values which are not safe to be logged and echoed are ignored
+func incomingRequestID(r *http.Request) string {
+	for _, key := range []string{HeaderRequestIDKey, HeaderXRequestIDKey, HeaderAmznRequestIDKey} {
+		if requestID := strings.TrimSpace(r.Header.Get(key)); isValidRequestID(requestID) {
+			return requestID
+		}
+	}
+	if traceID := traceIDFromTraceParent(r.Header.Get(HeaderTraceParentKey)); traceID != "" {
+		return traceID
+	}
+	return ""
+}
+
+// traceIDFromTraceParent return trace ID of "00-{trace-id}-{parent-id}-{flags}"
+func traceIDFromTraceParent(traceParent string) string {
+	parts := strings.Split(strings.TrimSpace(traceParent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 {
+		return ""
+	}
+	if !isHex(parts[1]) || strings.Trim(parts[1], "0") == "" {
+		return ""
+	}
+	return parts[1]
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// withRequestID keep request ID from client (or new one) in
+// request context and response header, so foundation context,
+// logger and error response use the same ID
+func withRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	if foundation.GetRequestIDFromContext(r.Context()) != "" {
+		return r
+	}
+	var requestID = incomingRequestID(r)
+	if requestID == "" {
+		requestID = xid.New().String()
+	}
+	w.Header().Set(HeaderRequestIDKey, requestID)
+	return r.WithContext(context.WithValue(r.Context(), foundation.FoundationRequestIDContextKey, requestID))
+}
+
+// accessTokenFromHeader return token of Authorization header
+// without "Bearer" scheme, the same as token in gRPC metadata
+func accessTokenFromHeader(r *http.Request) string {
+	var authorization = strings.TrimSpace(r.Header.Get(HeaderAuthorizationKey))
+	if len(authorization) > 7 && strings.EqualFold(authorization[:7], "bearer ") {
+		return strings.TrimSpace(authorization[7:])
+	}
+	return authorization
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"github.com/octofoxio/foundation"
+	"github.com/octofoxio/foundation/logger"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIncomingRequestID(t *testing.T) {
+	var request = func(key, value string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(key, value)
+		return r
+	}
+	assert.Equal(t, "abc-123", incomingRequestID(request(HeaderRequestIDKey, "abc-123")))
+	assert.Equal(t, "abc-123", incomingRequestID(request(HeaderXRequestIDKey, "abc-123")))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", incomingRequestID(request(HeaderTraceParentKey, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")))
+	assert.Equal(t, "", incomingRequestID(request(HeaderTraceParentKey, "00-00000000000000000000000000000000-00f067aa0ba902b7-01")))
+	assert.Equal(t, "", incomingRequestID(request(HeaderXRequestIDKey, "abc\r\nSet-Cookie: a=b")))
+	assert.Equal(t, "", incomingRequestID(request(HeaderXRequestIDKey, strings.Repeat("a", 129))))
+}
+
+func TestServer_RequestID(t *testing.T) {
+	var hook = &test.Hook{}
+	logger.AddHook(hook)
+
+	var s = NewServer()
+	s.Get("/me", func(ctx context.Context) (interface{}, error) {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		assert.Equal(t, "abc-123", foundation.GetRequestIDFromContext(ctx))
+		assert.Equal(t, []string{"abc-123"}, md.Get(foundation.GRPC_METADATA_REQUEST_ID_KEY))
+		assert.Equal(t, "token", foundation.GetAccessTokenFromContext(ctx))
+		assert.Empty(t, md.Get(foundation.GRPC_METADATA_AUTHORIZATION_KEY), "token is not forwarded by default")
+		return nil, writeResponse(ctx, http.StatusOK, []byte("me"))
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/me?fields=name", nil)
+	r.Header.Set(HeaderXRequestIDKey, "abc-123")
+	r.Header.Set(HeaderAuthorizationKey, "Bearer token")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "abc-123", w.Header().Get(HeaderRequestIDKey))
+
+	var access *logrus.Entry
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "access" && entry.Data["request-id"] == "abc-123" {
+			access = entry
+		}
+	}
+	if assert.NotNil(t, access) {
+		assert.Equal(t, logrus.InfoLevel, access.Level)
+		assert.Equal(t, http.StatusOK, access.Data["status"])
+		assert.Equal(t, 2, access.Data["size"])
+		assert.Equal(t, "/me?fields=name", access.Data["uri"])
+	}
+
+	// error response and 404 use the same request ID
+	r = httptest.NewRequest(http.MethodGet, "/missing", nil)
+	r.Header.Set(HeaderTraceParentKey, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", w.Header().Get(HeaderRequestIDKey))
+	assert.Contains(t, w.Body.String(), `"requestId":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.NotEmpty(t, w.Header().Get(HeaderRequestIDKey))
+}
+
+func TestForwardAuthorizationMiddleware(t *testing.T) {
+	var s = NewServer(WithMiddleware(ForwardAuthorizationMiddleware()))
+	s.Get("/me", func(ctx context.Context) (interface{}, error) {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		return nil, writeResponse(ctx, http.StatusOK, []byte(strings.Join(md.Get(foundation.GRPC_METADATA_AUTHORIZATION_KEY), ",")))
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/me", nil)
+	r.Header.Set(HeaderAuthorizationKey, "Bearer token")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, "token", w.Body.String())
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me", nil))
+	assert.Equal(t, "", w.Body.String())
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"github.com/octofoxio/foundation"
+	"github.com/octofoxio/foundation/errors"
+	"net/http"
+	"reflect"
+)
+
+// TypedHandler is handler which receive decoded input
+// and return output with their own types
+type TypedHandler[In any, Out any] func(ctx context.Context, input In) (Out, error)
+
+type Decoder[In any] func(ctx context.Context, r *http.Request) (In, error)
+type Encoder[Out any] func(ctx context.Context, output Out) (int, []byte, error)
+
+// Route bind decoder, handler and encoder with compile time types,
+// Decoder default to NegotiatedRequestDecoder and Encoder
+// default to NegotiatedResponseEncoder. Request and Response
+// of Doc default to In and Out
+//
+// ```
+// http.Handle(s, http.Route[*app.ConcatInput, *app.ConcatOutput]{
+// Method:  http.MethodGet,
+// Path:    "/concat",
+// Handler: stringsvc.Concat,
+// })
+// ```
+type Route[In any, Out any] struct {
+	Method     string
+	Path       string
+	Decoder    Decoder[In]
+	Handler    TypedHandler[In, Out]
+	Encoder    Encoder[Out]
+	Middleware []Middleware
+	Doc        RouteDoc
+}
+
+// Handle register typed route to server, middleware
+// are applied around decoding, handler and encoding
+func Handle[In any, Out any](s *Server, route Route[In, Out]) {
+	var (
+		decode = route.Decoder
+		encode = route.Encoder
+	)
+	if decode == nil {
+		decode = defaultDecoder[In]()
+	}
+	if encode == nil {
+		encode = EncodeAs[Out](NegotiatedResponseEncoder())
+	}
+	var doc = route.Doc
+	if doc.Request == nil {
+		doc.Request = new(In)
+	}
+	if doc.Response == nil {
+		doc.Response = new(Out)
+	}
+	r := s.registerHTTPHandler(route.Method, route.Path, func(ctx context.Context) (interface{}, error) {
+		input, err := decode(ctx, getRequestFromContext(ctx))
+		if err != nil {
+			foundation.GetLoggerFromContext(ctx).WithError(err).Warn("Request decoding error")
+			return nil, err
+		}
+		ctx = context.WithValue(ctx, InputBodyContextKey, input)
+		output, err := route.Handler(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		code, body, err := encode(ctx, output)
+		if err != nil {
+			return nil, err
+		}
+		return output, writeResponse(ctx, code, body)
+	}, route.Middleware...)
+	s.Document(r, doc)
+}
+
+// HandleFunc register typed handler with default decoder and encoder,
+// types are inferred from handler
+//
+// ```
+// http.HandleFunc(s, http.MethodGet, "/concat", stringsvc.Concat)
+// ```
+func HandleFunc[In any, Out any](s *Server, method string, path string, handler TypedHandler[In, Out], middleware ...Middleware) {
+	Handle(s, Route[In, Out]{
+		Method:     method,
+		Path:       path,
+		Handler:    handler,
+		Middleware: middleware,
+	})
+}
+
+// DecodeAs adapt RequestDecoder which return In (or pointer to In)
+func DecodeAs[In any](decoder RequestDecoder) Decoder[In] {
+	return func(ctx context.Context, r *http.Request) (In, error) {
+		var zero In
+		value, err := decoder(ctx, r)
+		if err != nil {
+			return zero, err
+		}
+		switch v := value.(type) {
+		case In:
+			return v, nil
+		case *In:
+			return *v, nil
+		}
+		return zero, errors.New(errors.ErrorTypeInternal, fmt.Sprintf("decoder return %T instead of %T", value, zero))
+	}
+}
+
+// EncodeAs adapt ResponseEncoder to typed Encoder
+func EncodeAs[Out any](encoder ResponseEncoder) Encoder[Out] {
+	return func(ctx context.Context, output Out) (int, []byte, error) {
+		return encoder(ctx, output)
+	}
+}
+
+func defaultDecoder[In any]() Decoder[In] {
+	var zero In
+	if t := reflect.TypeOf(zero); t != nil && t.Kind() == reflect.Ptr {
+		return DecodeAs[In](NegotiatedRequestDecoder(zero))
+	}
+	return DecodeAs[In](NegotiatedRequestDecoder(new(In)))
+}
+
+// TypedEndpoint is migration path from EndpointHandler, typed handler
+// can be used with RequestDecoderMiddleware and ResponseEncoderMiddleware
+// before the route is moved to Handle
+//
+// ```
+// s.Get("/concat", http.EndpointHandler(http.TypedEndpoint(stringsvc.Concat)), decoder, encoder)
+// ```
+func TypedEndpoint[In any, Out any](handler TypedHandler[In, Out]) EndpointFunc {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		input, ok := request.(In)
+		if !ok {
+			var zero In
+			return nil, errors.New(errors.ErrorTypeInternal, fmt.Sprintf("request is %T instead of %T, check RequestDecoderMiddleware", request, zero))
+		}
+		return handler(ctx, input)
+	}
+}
+
+// InputFromContext return input decoded by RequestDecoderMiddleware or Handle
+func InputFromContext[In any](ctx context.Context) (In, bool) {
+	input, ok := ctx.Value(InputBodyContextKey).(In)
+	return input, ok
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type greetInput struct {
+	Name string `json:"name"`
+}
+
+type greetOutput struct {
+	Message string `json:"message"`
+}
+
+type greeter struct{}
+
+func (greeter) Greet(ctx context.Context, input *greetInput) (*greetOutput, error) {
+	if input.Name == "" {
+		return nil, errors.New(errors.ErrorTypeBadInput, "name is required")
+	}
+	return &greetOutput{Message: "hello " + input.Name}, nil
+}
+
+func TestHandle(t *testing.T) {
+	var s = NewServer()
+	HandleFunc(s, http.MethodGet, "/greet", greeter{}.Greet)
+	Handle(s, Route[greetInput, greetOutput]{
+		Method: http.MethodPost,
+		Path:   "/greet/{name}",
+		Handler: func(ctx context.Context, input greetInput) (greetOutput, error) {
+			return greetOutput{Message: "hi " + input.Name}, nil
+		},
+		Encoder: EncodeAs[greetOutput](JSONResponseEncoder(WithStatusCode(http.StatusCreated))),
+		Middleware: []Middleware{func(next Handler) Handler {
+			return func(ctx context.Context) (interface{}, error) {
+				output, err := next(ctx)
+				input, ok := InputFromContext[greetInput](ctx)
+				assert.False(t, ok, "input is added inside the route")
+				assert.Equal(t, greetInput{}, input)
+				return output, err
+			}
+		}},
+	})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/greet?name=john", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message": "hello john"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/greet", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/greet/jane", strings.NewReader(`{"name": "ignored"}`))
+	r.Header.Set(HeaderContentTypeKey, ContentTypeJSON)
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var output greetOutput
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &output))
+	assert.Equal(t, "hi jane", output.Message, "path variable override body")
+}
+
+func TestTypedEndpoint(t *testing.T) {
+	var s = NewServer()
+	s.Get("/greet",
+		EndpointHandler(TypedEndpoint(greeter{}.Greet)),
+		RequestDecoderMiddleware(FormRequestDecoder(&greetInput{})),
+		ResponseEncoderMiddleware(JSONResponseEncoder()),
+	)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/greet?name=john", nil))
+	assert.JSONEq(t, `{"message": "hello john"}`, w.Body.String())
+
+	_, err := TypedEndpoint(greeter{}.Greet)(context.Background(), greetInput{})
+	assert.Error(t, err)
+}
+
+func TestDecodeAs(t *testing.T) {
+	var decoder = DecodeAs[greetInput](func(ctx context.Context, r *http.Request) (interface{}, error) {
+		return &greetInput{Name: "john"}, nil
+	})
+	input, err := decoder(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "john", input.Name)
+
+	_, err = DecodeAs[int](func(ctx context.Context, r *http.Request) (interface{}, error) {
+		return "x", nil
+	})(context.Background(), nil)
+	assert.Error(t, err)
+}
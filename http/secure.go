@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SecureHeadersOptions is configuration of SecureHeadersMiddleware,
+// X-Content-Type-Options is always nosniff. HSTS is sent only when
+// HSTSMaxAge is set and request is HTTPS (directly or by X-Forwarded-Proto)
+type SecureHeadersOptions struct {
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	ContentSecurityPolicy string
+	FrameOptions          string // default DENY
+	ReferrerPolicy        string // default no-referrer
+}
+
+// SecureHeadersMiddleware set security headers before handler,
+// so error responses have them as well
+//
+// ```
+// http.SecureHeadersMiddleware(http.SecureHeadersOptions{
+// HSTSMaxAge:            365 * 24 * time.Hour,
+// ContentSecurityPolicy: "default-src 'self'",
+// })
+// ```
+func SecureHeadersMiddleware(options SecureHeadersOptions) Middleware {
+	if options.FrameOptions == "" {
+		options.FrameOptions = "DENY"
+	}
+	if options.ReferrerPolicy == "" {
+		options.ReferrerPolicy = "no-referrer"
+	}
+	var hsts string
+	if options.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", int(options.HSTSMaxAge.Seconds()))
+		if options.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if options.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context) (interface{}, error) {
+			var (
+				r = getRequestFromContext(ctx)
+				h = getResponseWriterFromContext(ctx).Header()
+			)
+			h.Set("X-Content-Type-Options", "nosniff")
+			h.Set("X-Frame-Options", options.FrameOptions)
+			h.Set("Referrer-Policy", options.ReferrerPolicy)
+			if options.ContentSecurityPolicy != "" {
+				h.Set("Content-Security-Policy", options.ContentSecurityPolicy)
+			}
+			if hsts != "" && isHTTPS(r) {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+			return next(ctx)
+		}
+	}
+}
+
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ContentTypeEventStream = "text/event-stream"
+	ContentTypeNDJSON      = "application/x-ndjson"
+
+	HeaderLastEventIDKey = "Last-Event-ID"
+
+	// DefaultHeartbeat is interval of SSE comment which keep
+	// connection open through proxies when there is no event
+	DefaultHeartbeat = 15 * time.Second
+)
+
+// Event is Server-Sent Event, Data which is not string
+// or []byte is sent as JSON (jsonpb for proto messages)
+type Event struct {
+	ID    string
+	Event string
+	Data  interface{}
+	Retry time.Duration
+}
+
+func (e Event) marshal() ([]byte, error) {
+	var data []byte
+	switch d := e.Data.(type) {
+	case nil:
+	case string:
+		data = []byte(d)
+	case []byte:
+		data = d
+	default:
+		b, err := encodeJSONPB(d)
+		if err != nil {
+			return nil, err
+		}
+		data = b
+	}
+	var b bytes.Buffer
+	if e.ID != "" {
+		b.WriteString("id: " + singleLine(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + singleLine(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString(fmt.Sprintf("retry: %d\n", e.Retry.Milliseconds()))
+	}
+	for _, line := range strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return b.Bytes(), nil
+}
+
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+type SSEOptions struct {
+	Heartbeat time.Duration // default DefaultHeartbeat
+}
+
+// EventSource return events which are sent after lastEventID (empty
+// for new client), the channel must be closed when ctx is done
+type EventSource func(ctx context.Context, lastEventID string) (<-chan Event, error)
+
+// LastEventID return ID of the last event which reconnecting client received,
+// from Last-Event-ID header or lastEventId query param for EventSource polyfills
+func LastEventID(ctx context.Context) string {
+	r := getRequestFromContext(ctx)
+	if id := r.Header.Get(HeaderLastEventIDKey); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("lastEventId")
+}
+
+// SSEHandler stream events from source, error from source
+// is returned before the stream start as normal error response
+//
+// ```
+// s.Get("/orders/{id}/events", http.SSEHandler(func(ctx context.Context, lastEventID string) (<-chan http.Event, error) {
+// return orders.Watch(ctx, http.PathVar(ctx, "id"), lastEventID)
+// }, http.SSEOptions{}))
+// ```
+func SSEHandler(source EventSource, options SSEOptions) Handler {
+	return func(ctx context.Context) (interface{}, error) {
+		events, err := source(ctx, LastEventID(ctx))
+		if err != nil {
+			return nil, err
+		}
+		return nil, ServeSSE(ctx, events, options)
+	}
+}
+
+// ServeSSE write events until the channel is closed or client is gone,
+// comment is sent every heartbeat interval while there is no event
+func ServeSSE(ctx context.Context, events <-chan Event, options SSEOptions) error {
+	if options.Heartbeat <= 0 {
+		options.Heartbeat = DefaultHeartbeat
+	}
+	var w = getResponseWriterFromContext(ctx)
+	startStream(w, ContentTypeEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flush(w)
+
+	var heartbeat = time.NewTicker(options.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-heartbeat.C:
+			if _, err := w.Write([]byte(": heartbeat\n\n")); err != nil {
+				return err
+			}
+			flush(w)
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			b, err := event.marshal()
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(b); err != nil {
+				return err
+			}
+			flush(w)
+		}
+	}
+}
+
+func startStream(w http.ResponseWriter, contentType string) {
+	w.Header().Set(HeaderContentTypeKey, contentType)
+	w.Header().Del("Content-Length")
+}
+
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// NDJSONHandler stream items as newline delimited JSON, so large result
+// (e.g. database cursor) is not kept in memory. Error which is returned
+// before the first item is normal error response, after that it is
+// written as the last line in format of {"error": ErrorResponse}
+//
+// ```
+// s.Get("/users/export", http.NDJSONHandler(func(ctx context.Context, send func(*User) error) error {
+// return users.Each(ctx, send)
+// }))
+// ```
+func NDJSONHandler[T any](produce func(ctx context.Context, send func(item T) error) error) Handler {
+	return func(ctx context.Context) (interface{}, error) {
+		var (
+			w       = getResponseWriterFromContext(ctx)
+			started = false
+		)
+		err := produce(ctx, func(item T) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			b, err := encodeJSONPB(item)
+			if err != nil {
+				return err
+			}
+			if !started {
+				started = true
+				startStream(w, ContentTypeNDJSON)
+				w.WriteHeader(http.StatusOK)
+			}
+			if _, err := w.Write(append(b, '\n')); err != nil {
+				return err
+			}
+			flush(w)
+			return nil
+		})
+		if err != nil && started && ctx.Err() == nil {
+			b, _ := json.Marshal(map[string]interface{}{"error": NewErrorResponse(ctx, err)})
+			_, _ = w.Write(append(b, '\n'))
+			flush(w)
+		}
+		if err != nil && ctx.Err() != nil {
+			// client is gone
+			return nil, nil
+		}
+		if err == nil && !started {
+			startStream(w, ContentTypeNDJSON)
+			w.WriteHeader(http.StatusOK)
+		}
+		return nil, err
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvent_marshal(t *testing.T) {
+	b, err := Event{ID: "1", Event: "update", Data: "line 1\nline 2", Retry: time.Second}.marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, "id: 1\nevent: update\nretry: 1000\ndata: line 1\ndata: line 2\n\n", string(b))
+
+	b, err = Event{Data: map[string]int{"count": 1}}.marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, "data: {\"count\":1}\n\n", string(b))
+}
+
+func TestSSEHandler(t *testing.T) {
+	var s = NewServer()
+	s.Get("/events", SSEHandler(func(ctx context.Context, lastEventID string) (<-chan Event, error) {
+		if lastEventID == "bad" {
+			return nil, errors.New(errors.ErrorTypeBadInput, "invalid event ID")
+		}
+		var events = make(chan Event)
+		go func() {
+			defer close(events)
+			events <- Event{ID: lastEventID + "1", Data: "first"}
+			time.Sleep(30 * time.Millisecond)
+			events <- Event{ID: lastEventID + "2", Data: "second"}
+		}()
+		return events, nil
+	}, SSEOptions{Heartbeat: 10 * time.Millisecond}))
+
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	r.Header.Set(HeaderLastEventIDKey, "a")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ContentTypeEventStream, w.Header().Get(HeaderContentTypeKey))
+	assert.True(t, w.Flushed)
+	assert.True(t, strings.HasPrefix(w.Body.String(), "id: a1\ndata: first\n\n"))
+	assert.Contains(t, w.Body.String(), ": heartbeat\n\n")
+	assert.True(t, strings.HasSuffix(w.Body.String(), "id: a2\ndata: second\n\n"))
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events?lastEventId=bad", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestServeSSE_Cancel(t *testing.T) {
+	var s = NewServer()
+	var done = make(chan error, 1)
+	s.Get("/events", func(ctx context.Context) (interface{}, error) {
+		err := ServeSSE(ctx, make(chan Event), SSEOptions{})
+		done <- err
+		return nil, err
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx))
+	assert.NoError(t, <-done)
+}
+
+func TestNDJSONHandler(t *testing.T) {
+	var s = NewServer()
+	s.Get("/users", NDJSONHandler(func(ctx context.Context, send func(item greetOutput) error) error {
+		if getRequestFromContext(ctx).URL.Query().Get("fail") == "early" {
+			return errors.New(errors.ErrorTypeForbidden, "forbidden")
+		}
+		for _, name := range []string{"a", "b"} {
+			if err := send(greetOutput{Message: name}); err != nil {
+				return err
+			}
+		}
+		if getRequestFromContext(ctx).URL.Query().Get("fail") == "late" {
+			return errors.New(errors.ErrorTypeUnavailable, "database is gone")
+		}
+		return nil
+	}))
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ContentTypeNDJSON, w.Header().Get(HeaderContentTypeKey))
+	assert.Equal(t, "{\"message\":\"a\"}\n{\"message\":\"b\"}\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users?fail=early", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users?fail=late", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[2], `"error":{"code":503`)
+}
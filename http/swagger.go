@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"github.com/gorilla/mux"
+	"github.com/octofoxio/foundation"
+	"github.com/octofoxio/foundation/errors"
+	"html/template"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// SwaggerUICDN is base URL of swagger-ui-dist assets
+// used by ServeSwaggerUI when files is nil
+const SwaggerUICDN = "https://unpkg.com/swagger-ui-dist@3.52.5"
+
+var swaggerUIIndex = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" type="text/css" href="{{.Assets}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{.Assets}}/swagger-ui-bundle.js"></script>
+<script src="{{.Assets}}/swagger-ui-standalone-preset.js"></script>
+<script>
+  window.onload = function () {
+    window.ui = SwaggerUIBundle({
+      url: {{.SpecURL}},
+      dom_id: "#swagger-ui",
+      presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+      layout: "StandaloneLayout"
+    })
+  }
+</script>
+</body>
+</html>
+`))
+
+// ServeSwaggerUI serve Swagger UI at prefix for document at specURL,
+// assets of swagger-ui-dist are not bundled with foundation, they are
+// read from dir of files so they can be bundled into binary by statik
+// (see foundation.NewFileSystem), when files is nil the index page
+// load them from SwaggerUICDN instead
+//
+// ```
+// // statik -src=./swagger-ui -dest=./
+// s.ServeOpenAPI("/openapi.json", info)
+// s.ServeSwaggerUI("/docs", foundation.NewFileSystem("./", foundation.StaticMode_Statik), "/", "/openapi.json")
+// // or
+// s.ServeSwaggerUI("/docs", nil, "", "/openapi.json")
+// ```
+func (s *Server) ServeSwaggerUI(prefix string, files foundation.FileSystem, dir string, specURL string) *mux.Route {
+	prefix = strings.TrimSuffix(prefix, "/")
+	var fullPrefix = s.prefix + prefix
+	route := s.r.PathPrefix(prefix).Methods(http.MethodGet)
+	route.HandlerFunc(s.handlerFunc(http.MethodGet, fullPrefix, func(ctx context.Context) (interface{}, error) {
+		var (
+			r    = getRequestFromContext(ctx)
+			w    = getResponseWriterFromContext(ctx)
+			name = strings.TrimPrefix(r.URL.Path, fullPrefix)
+		)
+		switch name {
+		case "":
+			// assets are relative to the index
+			http.Redirect(w, r, fullPrefix+"/", http.StatusMovedPermanently)
+			return nil, nil
+		case "/", "/index.html":
+			w.Header().Set(HeaderContentTypeKey, "text/html; charset=utf-8")
+			var assets = "."
+			if files == nil {
+				assets = SwaggerUICDN
+			}
+			return nil, swaggerUIIndex.Execute(w, map[string]string{"Title": "Swagger UI", "SpecURL": specURL, "Assets": assets})
+		}
+		if files == nil {
+			return nil, ErrRouteNotFound.New(errors.Params{"method": r.Method, "path": r.URL.Path})
+		}
+		f, err := files.Open(path.Join("/", dir, name))
+		if err != nil {
+			return nil, ErrRouteNotFound.New(errors.Params{"method": r.Method, "path": r.URL.Path})
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			return nil, ErrRouteNotFound.New(errors.Params{"method": r.Method, "path": r.URL.Path})
+		}
+		http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+		return nil, nil
+	}))
+	return s.Document(route, RouteDoc{Hidden: true})
+}
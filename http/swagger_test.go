@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"github.com/octofoxio/foundation"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServer_ServeSwaggerUI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "swagger")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "swagger-ui"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "swagger-ui", "swagger-ui.css"), []byte("body {}"), 0644))
+
+	var s = NewServer()
+	s.Group("/api").ServeSwaggerUI("/docs/", foundation.NewFileSystem(dir, foundation.StaticMode_LOCAL), "swagger-ui", "/api/openapi.json")
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/api/docs/", w.Header().Get("Location"))
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `url: "/api/openapi.json"`)
+	assert.Contains(t, w.Body.String(), `href="./swagger-ui.css"`)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs/swagger-ui.css", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "body {}", w.Body.String())
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs/missing.js", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.Empty(t, s.OpenAPI(OpenAPIInfo{}).Paths)
+
+	t.Run("assets from CDN", func(t *testing.T) {
+		var s = NewServer()
+		s.ServeSwaggerUI("/docs", nil, "", "/openapi.json")
+
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `src="`+SwaggerUICDN+`/swagger-ui-bundle.js"`)
+
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/swagger-ui.css", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
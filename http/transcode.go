@@ -0,0 +1,400 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/descriptor"
+	"github.com/golang/protobuf/proto"
+	protobuf "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/gorilla/mux"
+	"github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/octofoxio/foundation"
+	"github.com/octofoxio/foundation/errors"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+var descriptorMessageType = reflect.TypeOf((*descriptor.Message)(nil)).Elem()
+
+// transcodeRoute is a HTTP binding of a gRPC method
+type transcodeRoute struct {
+	method       string
+	path         string
+	body         string
+	responseBody string
+}
+
+// RegisterGRPCService serve unary methods of gRPC service implementation
+// over HTTP/JSON, routes are from google.api.http annotations and methods
+// without annotation are served at POST /{package.Service}/{Method}.
+// interceptors run the same way as NewGRPCServer, request headers
+// are sent to them as incoming metadata. Streaming methods are skipped,
+// see GRPCStreamHandler for server streaming
+//
+// ```
+// http.RegisterGRPCService(httpServer, "app.String", stringsvc, foundation.WithContextServerInterceptor())
+// ```
+func RegisterGRPCService(s *Server, serviceName string, impl interface{}, interceptors ...grpc.UnaryServerInterceptor) error {
+	service, err := findServiceDescriptor(serviceName, impl)
+	if err != nil {
+		return err
+	}
+	var interceptor grpc.UnaryServerInterceptor
+	if len(interceptors) > 0 {
+		interceptor = grpc_middleware.ChainUnaryServer(interceptors...)
+	}
+	for _, method := range service.GetMethod() {
+		if method.GetClientStreaming() || method.GetServerStreaming() {
+			continue
+		}
+		fn := reflect.ValueOf(impl).MethodByName(camelCase(method.GetName()))
+		if !fn.IsValid() || !isUnaryMethod(fn.Type()) {
+			return fmt.Errorf("%T does not implement %s.%s", impl, serviceName, method.GetName())
+		}
+		var info = &grpc.UnaryServerInfo{
+			Server:     impl,
+			FullMethod: fmt.Sprintf("/%s/%s", serviceName, method.GetName()),
+		}
+		var doc = RouteDoc{
+			OperationID: service.GetName() + "_" + method.GetName(),
+			Tags:        []string{serviceName},
+			Request:     reflect.New(fn.Type().In(1).Elem()).Interface(),
+			Response:    reflect.New(fn.Type().Out(0).Elem()).Interface(),
+		}
+		for i, route := range transcodeRoutes(serviceName, method) {
+			if i > 0 {
+				// operation ID must be unique
+				doc.OperationID = fmt.Sprintf("%s_%s%d", service.GetName(), method.GetName(), i)
+			}
+			s.Document(s.registerHTTPHandler(route.method, route.path, transcodeHandler(route, fn, info, interceptor)), doc)
+		}
+	}
+	return nil
+}
+
+func isUnaryMethod(t reflect.Type) bool {
+	return t.NumIn() == 2 && t.In(0) == contextType && t.In(1).Kind() == reflect.Ptr && t.In(1).Implements(descriptorMessageType) &&
+		t.NumOut() == 2 && t.Out(0).Kind() == reflect.Ptr && t.Out(1) == errorType
+}
+
+// findServiceDescriptor find service in proto file of request messages of impl
+func findServiceDescriptor(serviceName string, impl interface{}) (*protobuf.ServiceDescriptorProto, error) {
+	t := reflect.TypeOf(impl)
+	for i := 0; i < t.NumMethod(); i++ {
+		fn := t.Method(i).Type
+		// first argument is the receiver
+		if fn.NumIn() != 3 || !fn.In(2).Implements(descriptorMessageType) || fn.In(2).Kind() != reflect.Ptr {
+			continue
+		}
+		input := reflect.New(fn.In(2).Elem()).Interface().(descriptor.Message)
+		fd, _ := descriptor.ForMessage(input)
+		for _, service := range fd.GetService() {
+			name := service.GetName()
+			if fd.GetPackage() != "" {
+				name = fd.GetPackage() + "." + name
+			}
+			if name == serviceName {
+				return service, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("service %s is not found in proto files of %T", serviceName, impl)
+}
+
+// transcodeRoutes return routes from google.api.http annotation,
+// including additional bindings
+func transcodeRoutes(serviceName string, method *protobuf.MethodDescriptorProto) []transcodeRoute {
+	var rule *annotations.HttpRule
+	if method.GetOptions() != nil {
+		if ext, err := proto.GetExtension(method.GetOptions(), annotations.E_Http); err == nil {
+			rule, _ = ext.(*annotations.HttpRule)
+		}
+	}
+	if rule == nil {
+		return []transcodeRoute{{
+			method: http.MethodPost,
+			path:   fmt.Sprintf("/%s/%s", serviceName, method.GetName()),
+			body:   "*",
+		}}
+	}
+	var routes []transcodeRoute
+	for _, r := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+		var route = transcodeRoute{body: r.GetBody(), responseBody: r.GetResponseBody()}
+		switch {
+		case r.GetGet() != "":
+			route.method, route.path = http.MethodGet, r.GetGet()
+		case r.GetPost() != "":
+			route.method, route.path = http.MethodPost, r.GetPost()
+		case r.GetPut() != "":
+			route.method, route.path = http.MethodPut, r.GetPut()
+		case r.GetPatch() != "":
+			route.method, route.path = http.MethodPatch, r.GetPatch()
+		case r.GetDelete() != "":
+			route.method, route.path = http.MethodDelete, r.GetDelete()
+		case r.GetCustom() != nil:
+			route.method, route.path = strings.ToUpper(r.GetCustom().GetKind()), r.GetCustom().GetPath()
+		default:
+			continue
+		}
+		route.path = muxPathTemplate(route.path)
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+var templateVariable = regexp.MustCompile(`{([^}=]+)(=([^}]*))?}`)
+
+// muxPathTemplate convert google.api.http path template
+// (e.g. "/v1/{name=shelves/*}/books/{id}:publish") to gorilla/mux template
+func muxPathTemplate(template string) string {
+	var segment = `[^/]+`
+	if i := strings.LastIndex(template, ":"); i > strings.LastIndex(template, "}") {
+		// template with verb, variables must not consume the verb
+		segment = `[^/:]+`
+	}
+	return templateVariable.ReplaceAllStringFunc(template, func(variable string) string {
+		match := templateVariable.FindStringSubmatch(variable)
+		if match[3] == "" {
+			return fmt.Sprintf("{%s:%s}", match[1], segment)
+		}
+		var parts []string
+		for _, part := range strings.Split(match[3], "/") {
+			switch part {
+			case "*":
+				parts = append(parts, segment)
+			case "**":
+				parts = append(parts, `.+`)
+			default:
+				parts = append(parts, regexp.QuoteMeta(part))
+			}
+		}
+		return fmt.Sprintf("{%s:%s}", match[1], strings.Join(parts, "/"))
+	})
+}
+
+func transcodeHandler(route transcodeRoute, fn reflect.Value, info *grpc.UnaryServerInfo, interceptor grpc.UnaryServerInterceptor) Handler {
+	var (
+		inputType = fn.Type().In(1).Elem()
+		encoder   = NegotiatedResponseEncoder()
+		config    = newCodecConfig(nil)
+	)
+	return func(ctx context.Context) (interface{}, error) {
+		r := getRequestFromContext(ctx)
+		input := reflect.New(inputType).Interface().(proto.Message)
+		if err := decodeTranscodeRequest(r, route, input, config); err != nil {
+			return nil, err
+		}
+
+		var handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+			out := fn.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(req)})
+			if err, _ := out[1].Interface().(error); err != nil {
+				return nil, err
+			}
+			return out[0].Interface(), nil
+		}
+		var (
+			output interface{}
+			err    error
+		)
+		ctx = metadata.NewIncomingContext(ctx, incomingMetadata(ctx, r))
+		if interceptor != nil {
+			output, err = interceptor(ctx, input, info, handler)
+		} else {
+			output, err = handler(ctx, input)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var response = output
+		if route.responseBody != "" {
+			if field, ok := protoField(reflect.ValueOf(output), route.responseBody); ok {
+				response = field.Interface()
+			}
+		}
+		code, body, err := encoder(ctx, response)
+		if err != nil {
+			return nil, err
+		}
+		return output, writeResponse(ctx, code, body)
+	}
+}
+
+// incomingMetadata convert request headers to gRPC metadata
+func incomingMetadata(ctx context.Context, r *http.Request) metadata.MD {
+	var md = metadata.MD{}
+	for key, values := range r.Header {
+		md.Append(key, values...)
+	}
+	if len(md.Get(foundation.GRPC_METADATA_REQUEST_ID_KEY)) == 0 {
+		if requestID := requestIDFromContext(ctx); requestID != "" {
+			md.Set(foundation.GRPC_METADATA_REQUEST_ID_KEY, requestID)
+		}
+	}
+	return md
+}
+
+func decodeTranscodeRequest(r *http.Request, route transcodeRoute, input proto.Message, c *codecConfig) error {
+	body, err := readBody(r, c)
+	if err != nil {
+		return err
+	}
+	if len(body) > 0 && route.body != "" {
+		if err := decodeTranscodeBody(r, route.body, body, input, c); err != nil {
+			return ErrInvalidRequestBody.Wrap(err, errors.Params{"error": err.Error()})
+		}
+	}
+	if route.body != "*" {
+		for key, values := range r.URL.Query() {
+			if err := setProtoField(reflect.ValueOf(input), key, values); err != nil {
+				return ErrInvalidRequestParam.Wrap(err, errors.Params{"error": err.Error()})
+			}
+		}
+	}
+	for key, value := range mux.Vars(r) {
+		if err := setProtoField(reflect.ValueOf(input), key, []string{value}); err != nil {
+			return ErrInvalidRequestParam.Wrap(err, errors.Params{"error": err.Error()})
+		}
+	}
+	return nil
+}
+
+func decodeTranscodeBody(r *http.Request, field string, body []byte, input proto.Message, c *codecConfig) error {
+	var isProtobuf = mediaType(r.Header.Get(HeaderContentTypeKey)) == ContentTypeProtobuf
+	if field == "*" {
+		if isProtobuf {
+			return decodeProtobuf(body, input, c)
+		}
+		return decodeJSONPB(body, input, c)
+	}
+	target, ok := protoField(reflect.ValueOf(input), field)
+	if !ok {
+		return fmt.Errorf("unknown body field %s", field)
+	}
+	if target.Kind() == reflect.Ptr && target.IsNil() {
+		target.Set(reflect.New(target.Type().Elem()))
+	}
+	if message, ok := target.Interface().(proto.Message); ok {
+		if isProtobuf {
+			return decodeProtobuf(body, message, c)
+		}
+		return decodeJSONPB(body, message, c)
+	}
+	return json.Unmarshal(body, target.Addr().Interface())
+}
+
+// protoField find field of message by proto field path (e.g. "user.id"),
+// nil messages in the path are created
+func protoField(message reflect.Value, path string) (reflect.Value, bool) {
+	var v = message
+	for _, name := range strings.Split(path, ".") {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+		field, ok := findProtoField(v, name)
+		if !ok {
+			return reflect.Value{}, false
+		}
+		v = field
+	}
+	return v, true
+}
+
+func findProtoField(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		for _, option := range strings.Split(t.Field(i).Tag.Get("protobuf"), ",") {
+			if option == "name="+name || option == "json="+name {
+				return v.Field(i), true
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// setProtoField set field from path or query param, enum
+// can be either number or name, unknown fields are ignored
+func setProtoField(message reflect.Value, path string, values []string) error {
+	field, ok := protoField(message, path)
+	if !ok {
+		return nil
+	}
+	if field.Kind() == reflect.Int32 && len(values) > 0 {
+		if enum, ok := proto.EnumValueMap(enumName(message, path))[values[0]]; ok {
+			field.SetInt(int64(enum))
+			return nil
+		}
+	}
+	if err := setValue(field, values); err != nil {
+		return fmt.Errorf("invalid field %s: %s", path, err)
+	}
+	return nil
+}
+
+// enumName return full name of enum type from protobuf tag of field
+func enumName(message reflect.Value, path string) string {
+	var (
+		names = strings.Split(path, ".")
+		v     = message
+	)
+	for i, name := range names {
+		v = reflect.Indirect(v)
+		t := v.Type()
+		for j := 0; j < t.NumField(); j++ {
+			tag := t.Field(j).Tag.Get("protobuf")
+			if !strings.Contains(","+tag+",", ",name="+name+",") && !strings.Contains(","+tag+",", ",json="+name+",") {
+				continue
+			}
+			if i == len(names)-1 {
+				for _, option := range strings.Split(tag, ",") {
+					if strings.HasPrefix(option, "enum=") {
+						return strings.TrimPrefix(option, "enum=")
+					}
+				}
+				return ""
+			}
+			v = v.Field(j)
+			break
+		}
+	}
+	return ""
+}
+
+// camelCase convert proto method name to Go method name
+func camelCase(name string) string {
+	var b strings.Builder
+	var upper = true
+	for _, c := range name {
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if upper && c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		upper = false
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	protobuf "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/octofoxio/foundation"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type pingService struct{}
+
+func (pingService) Ping(ctx context.Context, input *foundation.PingInput) (*foundation.PingOutput, error) {
+	if input.Greeting == "" {
+		return nil, errors.New(errors.ErrorTypeBadInput, "greeting is required")
+	}
+	return &foundation.PingOutput{Greeting: "pong " + input.Greeting}, nil
+}
+
+func TestRegisterGRPCService(t *testing.T) {
+	var s = NewServer()
+	var called []string
+	err := RegisterGRPCService(s, "grpc.Test", pingService{}, func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		called = append(called, info.FullMethod, strings.Join(md.Get("x-client"), ""))
+		return handler(ctx, req)
+	})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/grpc.Test/Ping", strings.NewReader(`{"greeting": "hello"}`))
+	r.Header.Set("X-Client", "web")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"greeting": "pong hello"}`, w.Body.String())
+	assert.Equal(t, []string{"/grpc.Test/Ping", "web"}, called)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/grpc.Test/Ping", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/grpc.Test/Ping", strings.NewReader(`{"greeting": 1}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), ErrInvalidRequestBody.Reason)
+
+	assert.Error(t, RegisterGRPCService(s, "grpc.Unknown", pingService{}))
+	assert.Error(t, RegisterGRPCService(s, "grpc.Test", struct{}{}))
+}
+
+func TestTranscodeRoutes(t *testing.T) {
+	method := &protobuf.MethodDescriptorProto{Name: proto.String("Ping"), Options: &protobuf.MethodOptions{}}
+	assert.Equal(t, []transcodeRoute{{method: http.MethodPost, path: "/grpc.Test/Ping", body: "*"}},
+		transcodeRoutes("grpc.Test", method))
+
+	assert.NoError(t, proto.SetExtension(method.Options, annotations.E_Http, &annotations.HttpRule{
+		Pattern: &annotations.HttpRule_Get{Get: "/v1/ping/{greeting}"},
+		AdditionalBindings: []*annotations.HttpRule{{
+			Pattern:      &annotations.HttpRule_Post{Post: "/v1/ping"},
+			Body:         "*",
+			ResponseBody: "greeting",
+		}},
+	}))
+	assert.Equal(t, []transcodeRoute{
+		{method: http.MethodGet, path: "/v1/ping/{greeting:[^/]+}"},
+		{method: http.MethodPost, path: "/v1/ping", body: "*", responseBody: "greeting"},
+	}, transcodeRoutes("grpc.Test", method))
+}
+
+func TestMuxPathTemplate(t *testing.T) {
+	assert.Equal(t, "/v1/users/{id:[^/]+}", muxPathTemplate("/v1/users/{id}"))
+	assert.Equal(t, "/v1/{name:shelves/[^/]+}/books", muxPathTemplate("/v1/{name=shelves/*}/books"))
+	assert.Equal(t, "/v1/files/{path:.+}", muxPathTemplate("/v1/files/{path=**}"))
+	assert.Equal(t, "/v1/books/{id:[^/:]+}:publish", muxPathTemplate("/v1/books/{id}:publish"))
+}
+
+func TestTranscodeHandler(t *testing.T) {
+	var s = NewServer()
+	fn := reflect.ValueOf(pingService{}).MethodByName("Ping")
+	info := &grpc.UnaryServerInfo{Server: pingService{}, FullMethod: "/grpc.Test/Ping"}
+	s.registerHTTPHandler(http.MethodGet, muxPathTemplate("/v1/ping/{greeting}"),
+		transcodeHandler(transcodeRoute{method: http.MethodGet, responseBody: "greeting"}, fn, info, nil))
+	s.registerHTTPHandler(http.MethodPut, muxPathTemplate("/v1/ping"),
+		transcodeHandler(transcodeRoute{method: http.MethodPut, body: "greeting"}, fn, info, nil))
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/ping/hello", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"pong hello"`, strings.TrimSpace(w.Body.String()))
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/v1/ping", strings.NewReader(`"hi"`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"greeting": "pong hi"}`, w.Body.String())
+}
+
+func TestCamelCase(t *testing.T) {
+	assert.Equal(t, "GetUser", camelCase("get_user"))
+	assert.Equal(t, "Ping", camelCase("Ping"))
+}
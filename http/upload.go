@@ -0,0 +1,346 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"github.com/octofoxio/foundation"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/rs/xid"
+	"hash"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	// DefaultUploadFileSize is maximum size of each uploaded file
+	DefaultUploadFileSize int64 = 32 << 20
+	DefaultUploadMaxFiles       = 10
+
+	// maximum size of non-file fields of the form
+	maxUploadFieldSize int64 = 1 << 20
+	// number of bytes which content type is detected from
+	sniffLength = 512
+)
+
+var (
+	ErrFileTooLarge        = errors.Define("FILE_TOO_LARGE", http.StatusRequestEntityTooLarge, "file {filename} is larger than {limit} bytes", "Uploaded file exceed size limit of the route")
+	ErrUnsupportedFileType = errors.Define("UNSUPPORTED_FILE_TYPE", errors.ErrorTypeBadInput, "file {filename} of type {contentType} is not allowed", "Uploaded file is not one of content types which the route accept")
+	ErrTooManyFiles        = errors.Define("TOO_MANY_FILES", http.StatusRequestEntityTooLarge, "request contain more than {limit} files", "Upload request contain more files than the route accept")
+	ErrFileRequired        = errors.Define("FILE_REQUIRED", errors.ErrorTypeBadInput, "file is required", "Upload request does not contain any file")
+)
+
+// UploadOptions is configuration of upload handler, AllowedContentTypes
+// accept wildcard subtype (e.g. "image/*") and empty allow every types.
+// Key default to {Prefix}/{xid}{extension of filename}
+type UploadOptions struct {
+	Storage             foundation.FileStorage
+	Prefix              string
+	Fields              []string // file fields to accept, empty accept every fields
+	MaxFileSize         int64    // default DefaultUploadFileSize
+	MaxFiles            int      // default DefaultUploadMaxFiles
+	AllowedContentTypes []string
+	Public              bool // store with public ACL and return object URL instead of pre-signed URL
+	Key                 func(ctx context.Context, field string, filename string) string
+}
+
+// UploadedFile is stored file, checksums are hex encoded
+type UploadedFile struct {
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	Key         string `json:"key"`
+	URL         string `json:"url"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	MD5         string `json:"md5"`
+}
+
+type UploadResult struct {
+	Files  []*UploadedFile   `json:"files"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// ReceiveUpload read multipart request of ctx part by part and stream each file
+// into storage, files are not buffered in memory (storage which is not
+// foundation.StreamFileStorage receive a temporary file). When any part
+// fail, files which are already stored are removed
+//
+// ```
+// s.Post("/avatars", func(ctx context.Context) (interface{}, error) {
+// result, err := http.ReceiveUpload(ctx, http.UploadOptions{Storage: storage, AllowedContentTypes: []string{"image/*"}})
+// if err != nil {
+// return nil, err
+// }
+// return users.SetAvatar(ctx, result.Files[0].Key)
+// })
+// ```
+func ReceiveUpload(ctx context.Context, options UploadOptions) (*UploadResult, error) {
+	if options.MaxFileSize <= 0 {
+		options.MaxFileSize = DefaultUploadFileSize
+	}
+	if options.MaxFiles <= 0 {
+		options.MaxFiles = DefaultUploadMaxFiles
+	}
+	var r = getRequestFromContext(ctx)
+	if contentType := r.Header.Get(HeaderContentTypeKey); mediaType(contentType) != ContentTypeMultipart {
+		return nil, ErrUnsupportedMediaType.New(errors.Params{"contentType": contentType})
+	}
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, ErrInvalidRequestBody.Wrap(err, errors.Params{"error": err.Error()})
+	}
+
+	var storage = foundation.WithContextFileStorage(options.Storage)
+	var result = &UploadResult{Fields: map[string]string{}}
+	var cleanup = func() {
+		for _, f := range result.Files {
+			if err := storage.RemoveObjectWithContext(detachContext(ctx), f.Key); err != nil {
+				foundation.GetLoggerFromContext(ctx).WithError(err).Warnf("cannot remove uploaded file %s", f.Key)
+			}
+		}
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			cleanup()
+			return nil, bodyReadError(err)
+		}
+		if part.FileName() == "" {
+			value, err := ioutil.ReadAll(io.LimitReader(part, maxUploadFieldSize+1))
+			if err != nil {
+				cleanup()
+				return nil, bodyReadError(err)
+			}
+			if int64(len(value)) > maxUploadFieldSize {
+				cleanup()
+				return nil, ErrRequestTooLarge.New(errors.Params{"limit": maxUploadFieldSize})
+			}
+			result.Fields[part.FormName()] = string(value)
+			continue
+		}
+		if len(options.Fields) > 0 && !contains(options.Fields, part.FormName()) {
+			continue
+		}
+		if len(result.Files) >= options.MaxFiles {
+			cleanup()
+			return nil, ErrTooManyFiles.New(errors.Params{"limit": options.MaxFiles})
+		}
+		file, err := storeUploadPart(ctx, storage, part, options)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		result.Files = append(result.Files, file)
+	}
+	if len(result.Files) == 0 {
+		return nil, ErrFileRequired.New(nil)
+	}
+	return result, nil
+}
+
+// UploadHandler receive upload and respond UploadResult as JSON with status 201
+func UploadHandler(options UploadOptions) Handler {
+	var encode = JSONResponseEncoder(WithStatusCode(http.StatusCreated))
+	return func(ctx context.Context) (interface{}, error) {
+		result, err := ReceiveUpload(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		code, body, err := encode(ctx, result)
+		if err != nil {
+			return nil, err
+		}
+		return result, writeResponse(ctx, code, body)
+	}
+}
+
+func storeUploadPart(ctx context.Context, storage foundation.FileStorageWithContext, part *multipart.Part, options UploadOptions) (*UploadedFile, error) {
+	var filename = path.Base(strings.Replace(part.FileName(), "\\", "/", -1))
+	var head = make([]byte, sniffLength)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, bodyReadError(err)
+	}
+	head = head[:n]
+
+	var contentType = uploadContentType(head, part.Header.Get(HeaderContentTypeKey))
+	if !allowContentType(options.AllowedContentTypes, contentType) {
+		return nil, ErrUnsupportedFileType.New(errors.Params{"filename": filename, "contentType": contentType})
+	}
+
+	var key = path.Join(options.Prefix, xid.New().String()+strings.ToLower(path.Ext(filename)))
+	if options.Key != nil {
+		key = options.Key(ctx, part.FormName(), filename)
+	}
+	var body = &uploadReader{
+		Reader:    io.MultiReader(bytes.NewReader(head), part),
+		filename:  filename,
+		remaining: options.MaxFileSize,
+		limit:     options.MaxFileSize,
+		sha256:    sha256.New(),
+		md5:       md5.New(),
+	}
+	if err := putUpload(ctx, storage, options, key, body); err != nil {
+		// storage may wrap error of reader
+		if body.err != nil {
+			return nil, body.err
+		}
+		return nil, err
+	}
+
+	var file = &UploadedFile{
+		Field:       part.FormName(),
+		Filename:    filename,
+		Key:         key,
+		ContentType: contentType,
+		Size:        body.size,
+		SHA256:      hex.EncodeToString(body.sha256.Sum(nil)),
+		MD5:         hex.EncodeToString(body.md5.Sum(nil)),
+	}
+	if options.Public {
+		file.URL, err = storage.GetObjectURLWithContext(ctx, key)
+	} else {
+		file.URL, err = storage.GetObjectPreSignURLWithContext(ctx, key)
+	}
+	if err != nil {
+		_ = storage.RemoveObjectWithContext(detachContext(ctx), key)
+		return nil, err
+	}
+	return file, nil
+}
+
+// putUpload stream body to storage, or spool it
+// to temporary file when storage need io.ReadSeeker
+func putUpload(ctx context.Context, storage foundation.FileStorageWithContext, options UploadOptions, key string, body io.Reader) error {
+	var public = options.Public
+	if s, ok := storage.(foundation.StreamFileStorageWithContext); ok {
+		if public {
+			return s.PutPublicObjectFromReaderWithContext(ctx, key, body)
+		}
+		return s.PutObjectFromReaderWithContext(ctx, key, body)
+	}
+	if s, ok := options.Storage.(foundation.StreamFileStorage); ok {
+		if public {
+			return s.PutPublicObjectFromReader(key, body)
+		}
+		return s.PutObjectFromReader(key, body)
+	}
+	f, err := ioutil.TempFile("", "upload-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+	if _, err := io.Copy(f, body); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if public {
+		return storage.PutPublicObjectFromReadSeekerWithContext(ctx, key, f)
+	}
+	return storage.PutObjectFromReadSeekerWithContext(ctx, key, f)
+}
+
+// detachContext keep values of ctx without its cancellation, so stored
+// files are still removed when the request is canceled
+func detachContext(ctx context.Context) context.Context {
+	return foundation.AppendLoggerToContext(context.Background(), foundation.GetLoggerFromContext(ctx))
+}
+
+// uploadContentType detect content type from content, declared
+// content type is used only when it cannot be detected
+func uploadContentType(head []byte, declared string) string {
+	var detected = mediaType(http.DetectContentType(head))
+	if detected != "application/octet-stream" || declared == "" {
+		return detected
+	}
+	if t, _, err := mime.ParseMediaType(declared); err == nil {
+		return t
+	}
+	return detected
+}
+
+func allowContentType(allowed []string, contentType string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == contentType || (strings.HasSuffix(a, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(a, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyReadError wrap error of reading request body
+// as ErrInvalidRequestBody unless it is already *errors.Error,
+// body over limit of http.MaxBytesReader is ErrRequestTooLarge
+func bodyReadError(err error) error {
+	if e, ok := err.(*errors.Error); ok {
+		// e.g. ErrRequestTooLarge from BodyLimitMiddleware
+		return e
+	}
+	var tooLarge *http.MaxBytesError
+	if stderrors.As(err, &tooLarge) {
+		return ErrRequestTooLarge.New(errors.Params{"limit": tooLarge.Limit})
+	}
+	return ErrInvalidRequestBody.Wrap(err, errors.Params{"error": err.Error()})
+}
+
+// uploadReader count size and compute checksums of file
+// while it is read by storage, it fail when file is over limit
+type uploadReader struct {
+	io.Reader
+	filename  string
+	remaining int64
+	limit     int64
+	size      int64
+	sha256    hash.Hash
+	md5       hash.Hash
+	err       error
+}
+
+func (u *uploadReader) Read(p []byte) (int, error) {
+	if u.err != nil {
+		return 0, u.err
+	}
+	// read one more byte to know that file is over limit
+	if int64(len(p)) > u.remaining+1 {
+		p = p[:u.remaining+1]
+	}
+	n, err := u.Reader.Read(p)
+	if int64(n) > u.remaining {
+		u.err = ErrFileTooLarge.New(errors.Params{"filename": u.filename, "limit": u.limit})
+		return 0, u.err
+	}
+	u.remaining -= int64(n)
+	u.size += int64(n)
+	u.sha256.Write(p[:n])
+	u.md5.Write(p[:n])
+	if err != nil && err != io.EOF {
+		u.err = bodyReadError(err)
+		return n, u.err
+	}
+	return n, err
+}
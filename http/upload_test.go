@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/octofoxio/foundation"
+	"github.com/stretchr/testify/assert"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// seekerOnlyStorage hide PutObjectFromReader of LocalFileStorage
+type seekerOnlyStorage struct {
+	foundation.FileStorage
+}
+
+type uploadPart struct {
+	field, filename, contentType, content string
+}
+
+func newUploadRequest(parts ...uploadPart) *http.Request {
+	var body bytes.Buffer
+	var w = multipart.NewWriter(&body)
+	for _, p := range parts {
+		if p.filename == "" {
+			_ = w.WriteField(p.field, p.content)
+			continue
+		}
+		var header = textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="`+p.field+`"; filename="`+p.filename+`"`)
+		if p.contentType != "" {
+			header.Set(HeaderContentTypeKey, p.contentType)
+		}
+		part, _ := w.CreatePart(header)
+		_, _ = part.Write([]byte(p.content))
+	}
+	_ = w.Close()
+	r := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	r.Header.Set(HeaderContentTypeKey, w.FormDataContentType())
+	return r
+}
+
+func TestUploadHandler(t *testing.T) {
+	var png = "\x89PNG\r\n\x1a\n" + strings.Repeat("x", 600)
+	for name, storage := range map[string]foundation.FileStorage{
+		"stream":   foundation.NewLocalFileStorage(t.TempDir()),
+		"seekable": seekerOnlyStorage{foundation.NewLocalFileStorage(t.TempDir())},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var s = NewServer()
+			s.Post("/upload", UploadHandler(UploadOptions{
+				Storage:             storage,
+				Prefix:              "avatars",
+				MaxFileSize:         1000,
+				MaxFiles:            2,
+				AllowedContentTypes: []string{"image/*", "application/pdf"},
+			}))
+
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, newUploadRequest(
+				uploadPart{field: "title", content: "me"},
+				uploadPart{field: "file", filename: `C:\photos\Me.PNG`, contentType: "text/plain", content: png},
+				uploadPart{field: "file", filename: "cv.pdf", contentType: "application/pdf", content: "%PDF-1.4"},
+			))
+			assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+			var result UploadResult
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+			assert.Equal(t, map[string]string{"title": "me"}, result.Fields)
+			assert.Len(t, result.Files, 2)
+
+			file := result.Files[0]
+			sum := sha256.Sum256([]byte(png))
+			assert.Equal(t, "Me.PNG", file.Filename)
+			assert.Equal(t, "image/png", file.ContentType, "content type is detected from content")
+			assert.Equal(t, int64(len(png)), file.Size)
+			assert.Equal(t, hex.EncodeToString(sum[:]), file.SHA256)
+			assert.Len(t, file.MD5, 32)
+			assert.True(t, strings.HasPrefix(file.Key, "avatars/") && strings.HasSuffix(file.Key, ".png"), file.Key)
+			assert.NotEmpty(t, file.URL)
+			stored, err := storage.GetObject(file.Key)
+			assert.NoError(t, err)
+			assert.Equal(t, png, string(stored))
+			assert.Equal(t, "application/pdf", result.Files[1].ContentType)
+
+			// file over limit, the first file is removed
+			w = httptest.NewRecorder()
+			s.ServeHTTP(w, newUploadRequest(
+				uploadPart{field: "file", filename: "a.png", content: png},
+				uploadPart{field: "file", filename: "b.png", content: png + strings.Repeat("x", 400)},
+			))
+			assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+			assert.Contains(t, w.Body.String(), ErrFileTooLarge.Reason)
+
+			for _, c := range []struct {
+				request *http.Request
+				status  int
+				reason  string
+			}{
+				{newUploadRequest(uploadPart{field: "file", filename: "a.txt", content: "hello"}), http.StatusBadRequest, ErrUnsupportedFileType.Reason},
+				{newUploadRequest(uploadPart{field: "title", content: "me"}), http.StatusBadRequest, ErrFileRequired.Reason},
+				{newUploadRequest(
+					uploadPart{field: "file", filename: "a.pdf", content: "%PDF-1.4"},
+					uploadPart{field: "file", filename: "b.pdf", content: "%PDF-1.4"},
+					uploadPart{field: "file", filename: "c.pdf", content: "%PDF-1.4"},
+				), http.StatusRequestEntityTooLarge, ErrTooManyFiles.Reason},
+				{httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(`{}`)), http.StatusUnsupportedMediaType, ErrUnsupportedMediaType.Reason},
+			} {
+				w = httptest.NewRecorder()
+				s.ServeHTTP(w, c.request)
+				assert.Equal(t, c.status, w.Code)
+				assert.Contains(t, w.Body.String(), c.reason)
+			}
+		})
+	}
+}
+
+func TestUploadHandler_Cleanup(t *testing.T) {
+	var storage = foundation.NewLocalFileStorage(t.TempDir())
+	var keys []string
+	var s = NewServer()
+	s.Post("/upload", UploadHandler(UploadOptions{
+		Storage:     storage,
+		MaxFileSize: 10,
+		Key: func(ctx context.Context, field string, filename string) string {
+			keys = append(keys, field+"/"+filename)
+			return field + "/" + filename
+		},
+	}))
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, newUploadRequest(
+		uploadPart{field: "a", filename: "small.txt", content: "ok"},
+		uploadPart{field: "b", filename: "large.txt", content: strings.Repeat("x", 11)},
+	))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, []string{"a/small.txt", "b/large.txt"}, keys)
+	for _, key := range keys {
+		exists, err := storage.Exists(key)
+		assert.NoError(t, err)
+		assert.False(t, exists, key)
+	}
+}
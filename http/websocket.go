@@ -0,0 +1,371 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/octofoxio/foundation"
+	"github.com/octofoxio/foundation/errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWebSocketPingInterval = 30 * time.Second
+	DefaultWebSocketWriteTimeout = 10 * time.Second
+
+	// maximum length of close reason in close frame
+	maxCloseReasonLength = 123
+)
+
+var ErrServerShuttingDown = errors.Define("SERVER_SHUTTING_DOWN", errors.ErrorTypeUnavailable, "server is shutting down", "Server does not accept new WebSocket connection while shutting down")
+
+// WebSocketCodec frame messages of WebSocketConn,
+// MessageType is websocket.TextMessage or websocket.BinaryMessage
+type WebSocketCodec interface {
+	MessageType() int
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonWebSocketCodec struct{}
+
+func (jsonWebSocketCodec) MessageType() int { return websocket.TextMessage }
+func (jsonWebSocketCodec) Marshal(v interface{}) ([]byte, error) {
+	return encodeJSONPB(v)
+}
+func (jsonWebSocketCodec) Unmarshal(data []byte, v interface{}) error {
+	if _, ok := v.(proto.Message); !ok {
+		return json.Unmarshal(data, v)
+	}
+	return decodeJSONPB(data, v, newCodecConfig(nil))
+}
+
+type protobufWebSocketCodec struct{}
+
+func (protobufWebSocketCodec) MessageType() int { return websocket.BinaryMessage }
+func (protobufWebSocketCodec) Marshal(v interface{}) ([]byte, error) {
+	return encodeProtobuf(v)
+}
+func (protobufWebSocketCodec) Unmarshal(data []byte, v interface{}) error {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return errors.New(errors.ErrorTypeInternal, "message is not proto.Message")
+	}
+	return proto.Unmarshal(data, message)
+}
+
+var (
+	// JSONWebSocketCodec send text messages, proto messages are encoded by jsonpb
+	// and other values by encoding/json
+	JSONWebSocketCodec WebSocketCodec = jsonWebSocketCodec{}
+	// ProtobufWebSocketCodec send binary messages of proto messages
+	ProtobufWebSocketCodec WebSocketCodec = protobufWebSocketCodec{}
+)
+
+// WebSocketOptions is configuration of WebSocket route, CheckOrigin
+// default to allow only the same origin as the request host
+type WebSocketOptions struct {
+	CheckOrigin       func(r *http.Request) bool
+	Subprotocols      []string
+	Codec             WebSocketCodec // default JSONWebSocketCodec
+	ReadLimit         int64          // default DefaultBodyLimit
+	PingInterval      time.Duration  // default DefaultWebSocketPingInterval
+	WriteTimeout      time.Duration  // default DefaultWebSocketWriteTimeout
+	EnableCompression bool
+}
+
+// WebSocketHandler serve upgraded connection, ctx is the foundation
+// context of the request (request ID, logger and values from middleware)
+// and it is done when the server shutting down. Returned error close
+// the connection with code 4000 + HTTP status of the error
+type WebSocketHandler func(ctx context.Context, conn *WebSocketConn) error
+
+// WebSocketConn is WebSocket connection with message framing
+// by codec, Write is safe to be called concurrently
+type WebSocketConn struct {
+	conn      *websocket.Conn
+	codec     WebSocketCodec
+	options   WebSocketOptions
+	writeMu   *sync.Mutex
+	closeSent bool
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func (c *WebSocketConn) Context() context.Context {
+	return c.ctx
+}
+
+func (c *WebSocketConn) Subprotocol() string {
+	return c.conn.Subprotocol()
+}
+
+func (c *WebSocketConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// Read wait for the next message and decode it into v,
+// io.EOF is returned when client close the connection normally,
+// handler can still Write until it return (half-close)
+func (c *WebSocketConn) Read(v interface{}) error {
+	_, data, err := c.conn.ReadMessage()
+	if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+		return io.EOF
+	}
+	if err != nil {
+		return err
+	}
+	if err := c.codec.Unmarshal(data, v); err != nil {
+		return ErrInvalidRequestBody.Wrap(err, errors.Params{"error": err.Error()})
+	}
+	return nil
+}
+
+// Write encode v and send it as a message
+func (c *WebSocketConn) Write(v interface{}) error {
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.conn.SetWriteDeadline(time.Now().Add(c.options.WriteTimeout)); err != nil {
+		return err
+	}
+	return c.conn.WriteMessage(c.codec.MessageType(), data)
+}
+
+// Close send close frame with code (e.g. websocket.CloseNormalClosure)
+// and reason, then close the connection
+func (c *WebSocketConn) Close(code int, reason string) error {
+	c.sendClose(code, reason)
+	return c.conn.Close()
+}
+
+// sendClose send close frame once and let handler finish reading
+func (c *WebSocketConn) sendClose(code int, reason string) {
+	if len(reason) > maxCloseReasonLength {
+		reason = reason[:maxCloseReasonLength]
+	}
+	c.cancel()
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return
+	}
+	c.closeSent = true
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(c.options.WriteTimeout))
+}
+
+// keepalive ping client and close connection which does not answer in two intervals
+func (c *WebSocketConn) keepalive() {
+	var wait = 2 * c.options.PingInterval
+	_ = c.conn.SetReadDeadline(time.Now().Add(wait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wait))
+	})
+	var ticker = time.NewTicker(c.options.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.options.WriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// webSockets keep open connections of server and its groups
+type webSockets struct {
+	mux     *sync.Mutex
+	conns   map[*WebSocketConn]struct{}
+	wg      *sync.WaitGroup
+	closing bool
+}
+
+func newWebSockets() *webSockets {
+	return &webSockets{
+		mux:   &sync.Mutex{},
+		conns: map[*WebSocketConn]struct{}{},
+		wg:    &sync.WaitGroup{},
+	}
+}
+
+func (ws *webSockets) add(c *WebSocketConn) bool {
+	ws.mux.Lock()
+	defer ws.mux.Unlock()
+	if ws.closing {
+		return false
+	}
+	ws.conns[c] = struct{}{}
+	ws.wg.Add(1)
+	return true
+}
+
+func (ws *webSockets) remove(c *WebSocketConn) {
+	ws.mux.Lock()
+	defer ws.mux.Unlock()
+	if _, ok := ws.conns[c]; ok {
+		delete(ws.conns, c)
+		ws.wg.Done()
+	}
+}
+
+func (ws *webSockets) isClosing() bool {
+	ws.mux.Lock()
+	defer ws.mux.Unlock()
+	return ws.closing
+}
+
+func (ws *webSockets) list() []*WebSocketConn {
+	ws.mux.Lock()
+	defer ws.mux.Unlock()
+	var result = make([]*WebSocketConn, 0, len(ws.conns))
+	for c := range ws.conns {
+		result = append(result, c)
+	}
+	return result
+}
+
+// WebSocket register route which upgrade request to WebSocket,
+// middleware run before the upgrade so they can reject the request
+// (e.g. authentication) and add values to the context of handler
+//
+// ```
+// s.WebSocket("/chat", func(ctx context.Context, conn *http.WebSocketConn) error {
+// for {
+// var message ChatMessage
+// if err := conn.Read(&message); err != nil {
+// return err
+// }
+// rooms.Broadcast(foundation.GetUserIDFromContext(ctx), message)
+// }
+// }, http.WebSocketOptions{}, authMiddleware)
+// ```
+func (s *Server) WebSocket(path string, handler WebSocketHandler, options WebSocketOptions, middleware ...Middleware) *mux.Route {
+	if options.Codec == nil {
+		options.Codec = JSONWebSocketCodec
+	}
+	if options.ReadLimit <= 0 {
+		options.ReadLimit = DefaultBodyLimit
+	}
+	if options.PingInterval <= 0 {
+		options.PingInterval = DefaultWebSocketPingInterval
+	}
+	if options.WriteTimeout <= 0 {
+		options.WriteTimeout = DefaultWebSocketWriteTimeout
+	}
+	var upgrader = websocket.Upgrader{
+		CheckOrigin:       options.CheckOrigin,
+		Subprotocols:      options.Subprotocols,
+		EnableCompression: options.EnableCompression,
+	}
+	var registry = s.webSockets
+	return s.registerHTTPHandler(http.MethodGet, path, func(ctx context.Context) (interface{}, error) {
+		if registry.isClosing() {
+			return nil, ErrServerShuttingDown.New(nil)
+		}
+		var (
+			r        = getRequestFromContext(ctx)
+			u        = upgrader
+			rejected error
+		)
+		// handshake error is written by error encoder, status is the same as HTTP status
+		u.Error = func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+			rejected = errors.New(errors.ErrorType(status), reason.Error())
+		}
+		// headers from middleware (e.g. request ID) are written with the handshake
+		var w = getResponseWriterFromContext(ctx)
+		raw, err := u.Upgrade(w, r, w.Header())
+		if rejected != nil {
+			return nil, rejected
+		}
+		if err != nil {
+			return nil, err
+		}
+		raw.SetReadLimit(options.ReadLimit)
+		// close frame is answered when handler return, not when it is received
+		raw.SetCloseHandler(func(code int, text string) error { return nil })
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		var conn = &WebSocketConn{
+			conn:    raw,
+			codec:   options.Codec,
+			options: options,
+			writeMu: &sync.Mutex{},
+			ctx:     ctx,
+			cancel:  cancel,
+		}
+		if !registry.add(conn) {
+			_ = conn.Close(websocket.CloseTryAgainLater, ErrServerShuttingDown.Message)
+			return nil, nil
+		}
+		defer registry.remove(conn)
+		defer func() {
+			// recovery middleware cannot write to hijacked connection
+			if p := recover(); p != nil {
+				_ = conn.Close(websocket.CloseInternalServerErr, "")
+				panic(p)
+			}
+		}()
+		go conn.keepalive()
+
+		err = handler(ctx, conn)
+		if err != nil && err != io.EOF {
+			response := NewErrorResponse(ctx, err)
+			foundation.GetLoggerFromContext(ctx).WithError(err).Warn("WebSocket handler error")
+			_ = conn.Close(4000+response.Code, response.Message)
+			return nil, err
+		}
+		_ = conn.Close(websocket.CloseNormalClosure, "")
+		return nil, nil
+	}, middleware...)
+}
+
+// Shutdown send close frame (going away) to every WebSocket connections
+// and wait until their handlers return, connections which are still open
+// when ctx is done are closed immediately. It should be registered
+// to http.Server since http.Server.Shutdown does not close hijacked connections
+//
+// ```
+// srv := &http2.Server{Addr: ":3009", Handler: httpServer}
+// srv.RegisterOnShutdown(func() { _ = httpServer.Shutdown(ctx) })
+// ```
+func (s *Server) Shutdown(ctx context.Context) error {
+	var registry = s.webSockets
+	registry.mux.Lock()
+	registry.closing = true
+	registry.mux.Unlock()
+	for _, c := range registry.list() {
+		c.sendClose(websocket.CloseGoingAway, "server is shutting down")
+	}
+
+	var done = make(chan struct{})
+	go func() {
+		registry.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		for _, c := range registry.list() {
+			_ = c.conn.Close()
+		}
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package http
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	"github.com/octofoxio/foundation"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func dialWebSocket(t *testing.T, server *httptest.Server, path string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	return websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+path, header)
+}
+
+func TestServer_WebSocket(t *testing.T) {
+	var s = NewServer()
+	var auth = func(next Handler) Handler {
+		return func(ctx context.Context) (interface{}, error) {
+			if getRequestFromContext(ctx).Header.Get(HeaderAuthorizationKey) == "" {
+				return nil, errors.New(errors.ErrorTypeAuth, "unauthorized")
+			}
+			return next(foundation.AppendUserIDToContext(ctx, "user-1"))
+		}
+	}
+	s.WebSocket("/echo", func(ctx context.Context, conn *WebSocketConn) error {
+		for {
+			var input greetInput
+			if err := conn.Read(&input); err != nil {
+				return err
+			}
+			if input.Name == "" {
+				return errors.New(errors.ErrorTypeBadInput, "name is required")
+			}
+			if err := conn.Write(greetOutput{Message: foundation.GetUserIDFromContext(ctx) + ": " + input.Name}); err != nil {
+				return err
+			}
+		}
+	}, WebSocketOptions{PingInterval: 50 * time.Millisecond}, auth)
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	_, resp, err := dialWebSocket(t, server, "/echo", nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	var header = http.Header{HeaderAuthorizationKey: {"Bearer token"}, HeaderXRequestIDKey: {"ws-1"}}
+	conn, resp, err := dialWebSocket(t, server, "/echo", header)
+	assert.NoError(t, err)
+	assert.Equal(t, "ws-1", resp.Header.Get(HeaderRequestIDKey))
+	var pinged = make(chan struct{}, 1)
+	conn.SetPingHandler(func(data string) error {
+		select {
+		case pinged <- struct{}{}:
+		default:
+		}
+		_ = conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		return nil
+	})
+
+	assert.NoError(t, conn.WriteJSON(greetInput{Name: "john"}))
+	var output greetOutput
+	assert.NoError(t, conn.ReadJSON(&output))
+	assert.Equal(t, "user-1: john", output.Message)
+
+	// pings are handled while client reading
+	time.Sleep(70 * time.Millisecond)
+	assert.NoError(t, conn.WriteJSON(greetInput{}))
+	_, _, err = conn.ReadMessage()
+	assert.Len(t, pinged, 1)
+	assert.True(t, websocket.IsCloseError(err, 4400), err)
+	assert.Contains(t, err.Error(), "name is required")
+
+	header.Set("Origin", "https://evil.com")
+	_, resp, err = dialWebSocket(t, server, "/echo", header)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	var s = NewServer()
+	var started = make(chan struct{})
+	var finished = make(chan error, 1)
+	s.WebSocket("/wait", func(ctx context.Context, conn *WebSocketConn) error {
+		close(started)
+		var v interface{}
+		err := conn.Read(&v)
+		finished <- err
+		return err
+	}, WebSocketOptions{})
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	conn, _, err := dialWebSocket(t, server, "/wait", nil)
+	assert.NoError(t, err)
+	<-started
+
+	go func() {
+		// client answer close frame like browsers do
+		_, _, err := conn.ReadMessage()
+		if closeErr, ok := err.(*websocket.CloseError); ok {
+			assert.Equal(t, websocket.CloseGoingAway, closeErr.Code)
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(closeErr.Code, ""))
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, s.Shutdown(ctx))
+	assert.Equal(t, io.EOF, <-finished)
+
+	_, resp, err := dialWebSocket(t, server, "/wait", nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
@@ -111,17 +111,19 @@ func (g *globalLogFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	b.WriteString("\n")
 	return b.Bytes(), nil
 }
-func newPrivateLogger(output io.Writer) *logrus.Logger {
+func newPrivateLogger(output io.Writer, formatter logrus.Formatter) *logrus.Logger {
 	var log = &logrus.Logger{
 		Out:       output,
-		Formatter: &globalLogFormatter{},
+		Formatter: NewRedactFormatter(formatter),
+		Hooks:     logrus.LevelHooks{},
 		Level:     logrus.DebugLevel,
 	}
+	log.AddHook(globalHook{})
 	return log
 }
 func newLogger(name string) *Logger {
 	var log = &Logger{
-		FieldLogger: newPrivateLogger(os.Stdout),
+		FieldLogger: newPrivateLogger(os.Stdout, &globalLogFormatter{}),
 		Name:        name,
 		Data:        map[string]interface{}{},
 		isInitial:   true,
@@ -142,8 +144,34 @@ type Logger struct {
 	mux                *sync.Mutex
 }
 
+// privateLogger return logrus instance behind this logger
+func (g Logger) privateLogger() *logrus.Logger {
+	switch l := g.FieldLogger.(type) {
+	case *logrus.Entry:
+		return l.Logger
+	case *logrus.Logger:
+		return l
+	}
+	return nil
+}
+
 func (g Logger) SetOutput(w io.Writer) *Logger {
-	g.FieldLogger = newPrivateLogger(w).WithFields(g.Data)
+	var formatter logrus.Formatter = &globalLogFormatter{}
+	if l := g.privateLogger(); l != nil {
+		formatter = l.Formatter
+	}
+	g.FieldLogger = newPrivateLogger(w, formatter).WithFields(g.Data)
+	return &g
+}
+
+// SetFormatter change log format (e.g. &logrus.JSONFormatter{}),
+// values are still redacted by the current RedactionPolicy
+func (g Logger) SetFormatter(formatter logrus.Formatter) *Logger {
+	var output io.Writer = os.Stdout
+	if l := g.privateLogger(); l != nil {
+		output = l.Out
+	}
+	g.FieldLogger = newPrivateLogger(output, formatter).WithFields(g.Data)
 	return &g
 }
 
@@ -0,0 +1,43 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package logger
+
+import (
+	"github.com/sirupsen/logrus"
+	"sync"
+)
+
+var (
+	hooksMux = &sync.RWMutex{}
+	hooks    = logrus.LevelHooks{}
+)
+
+// AddHook register hook to every logger, including
+// loggers which already created (e.g. the one inside foundation context)
+func AddHook(hook logrus.Hook) {
+	hooksMux.Lock()
+	defer hooksMux.Unlock()
+	hooks.Add(hook)
+}
+
+// globalHook is attached to every private logrus instance
+// and dispatch entries to hooks registered by AddHook
+type globalHook struct{}
+
+func (globalHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (globalHook) Fire(entry *logrus.Entry) error {
+	hooksMux.RLock()
+	var levelHooks = hooks[entry.Level]
+	hooksMux.RUnlock()
+	for _, hook := range levelHooks {
+		if err := hook.Fire(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,317 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package logger
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const defaultRedactionMask = "[REDACTED]"
+
+// Redactor is implemented by values which know how to hide
+// their own sensitive content, the returned value is logged
+// instead of the original one
+type Redactor interface {
+	Redact() interface{}
+}
+
+// RedactionPolicy describe which values must be masked
+// before a log entry is written
+//   - Keys are matched case-insensitively against field names (and struct/map keys),
+//     a field is masked when its name (split by "_", "-", "." and camelCase) end with
+//     the key, e.g. "token" match "access_token" and "accessToken" but "tokens_count"
+//     and "password_reset_at" are not masked
+//   - Patterns are replaced inside every string value and log message
+//   - CardPatterns are replaced only when digits of the match pass Luhn check,
+//     so order IDs and phone numbers of the same length are kept
+type RedactionPolicy struct {
+	Keys         []string
+	Patterns     []*regexp.Regexp
+	CardPatterns []*regexp.Regexp
+	Mask         string
+}
+
+var (
+	redactionMux    = &sync.RWMutex{}
+	redactionPolicy = DefaultRedactionPolicy()
+)
+
+// DefaultRedactionPolicy mask common credential field names,
+// JWTs, credit card numbers and AWS access key IDs
+func DefaultRedactionPolicy() *RedactionPolicy {
+	return &RedactionPolicy{
+		Keys: []string{"authorization", "password", "token", "secret"},
+		Patterns: []*regexp.Regexp{
+			// JWT
+			regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+			// AWS access key ID
+			regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+		},
+		CardPatterns: []*regexp.Regexp{
+			// Visa, Master, JCB ... and Amex
+			regexp.MustCompile(`\b(?:\d{4}[- ]?){3}\d{4}\b`),
+			regexp.MustCompile(`\b3[47]\d{2}[- ]?\d{6}[- ]?\d{5}\b`),
+		},
+		Mask: defaultRedactionMask,
+	}
+}
+
+// SetRedactionPolicy replace the policy used by every logger,
+// set nil to disable redaction
+func SetRedactionPolicy(policy *RedactionPolicy) {
+	redactionMux.Lock()
+	redactionPolicy = policy
+	redactionMux.Unlock()
+}
+
+func currentRedactionPolicy() *RedactionPolicy {
+	redactionMux.RLock()
+	defer redactionMux.RUnlock()
+	return redactionPolicy
+}
+
+func (p *RedactionPolicy) mask() string {
+	if p.Mask == "" {
+		return defaultRedactionMask
+	}
+	return p.Mask
+}
+
+func (p *RedactionPolicy) isSensitiveKey(key string) bool {
+	var segments = keySegments(key)
+	for _, k := range p.Keys {
+		if k != "" && endWithSegments(segments, keySegments(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// keySegments split key by "_", "-", "." and camelCase into lower case words
+func keySegments(key string) []string {
+	var (
+		segments []string
+		current  []rune
+		runes    = []rune(key)
+	)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == '.' || r == ' ':
+			if len(current) > 0 {
+				segments = append(segments, string(current))
+			}
+			current = nil
+			continue
+		case unicode.IsUpper(r) && len(current) > 0:
+			// "accessToken" and "HTTPHeader" but not "ID"
+			if !unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				segments = append(segments, string(current))
+				current = nil
+			}
+		}
+		current = append(current, unicode.ToLower(r))
+	}
+	if len(current) > 0 {
+		segments = append(segments, string(current))
+	}
+	return segments
+}
+
+func endWithSegments(segments []string, suffix []string) bool {
+	if len(suffix) == 0 || len(suffix) > len(segments) {
+		return false
+	}
+	var offset = len(segments) - len(suffix)
+	for i := range suffix {
+		if segments[offset+i] != suffix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// RedactString replace every pattern match in s with the mask
+func (p *RedactionPolicy) RedactString(s string) string {
+	for _, pattern := range p.Patterns {
+		s = pattern.ReplaceAllString(s, p.mask())
+	}
+	for _, pattern := range p.CardPatterns {
+		s = pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if !luhn(match) {
+				return match
+			}
+			return p.mask()
+		})
+	}
+	return s
+}
+
+// luhn validate check digit of card number, separators are ignored
+func luhn(number string) bool {
+	var (
+		sum    = 0
+		digits = 0
+		double = false
+	)
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+	return digits > 0 && sum%10 == 0
+}
+
+// RedactFields return a copy of fields with sensitive values masked
+func (p *RedactionPolicy) RedactFields(fields logrus.Fields) logrus.Fields {
+	var result = make(logrus.Fields, len(fields))
+	for k, v := range fields {
+		result[k] = p.RedactValue(k, v)
+	}
+	return result
+}
+
+// RedactValue return value which safe to be logged under key
+func (p *RedactionPolicy) RedactValue(key string, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	if p.isSensitiveKey(key) {
+		return p.mask()
+	}
+	switch v := value.(type) {
+	case Redactor:
+		return v.Redact()
+	case string:
+		return p.RedactString(v)
+	case error:
+		return p.RedactString(v.Error())
+	case fmt.Stringer:
+		return p.RedactString(v.String())
+	}
+	if redacted, ok := p.redactReflect(reflect.ValueOf(value), 0); ok {
+		return redacted
+	}
+	return value
+}
+
+// redactReflect walk into maps and structs, it only return ok
+// when something inside has been masked, so the original value
+// is printed as-is in common case
+func (p *RedactionPolicy) redactReflect(v reflect.Value, depth int) (interface{}, bool) {
+	if depth > 8 {
+		return nil, false
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+
+	var (
+		result  = map[string]interface{}{}
+		changed = false
+	)
+	redactEntry := func(key string, value reflect.Value) {
+		if !value.CanInterface() {
+			return
+		}
+		if p.isSensitiveKey(key) {
+			result[key] = p.mask()
+			changed = true
+			return
+		}
+		var original = value.Interface()
+		switch o := original.(type) {
+		case Redactor:
+			result[key] = o.Redact()
+			changed = true
+			return
+		case string:
+			if s := p.RedactString(o); s != o {
+				result[key] = s
+				changed = true
+				return
+			}
+		}
+		if redacted, ok := p.redactReflect(value, depth+1); ok {
+			result[key] = redacted
+			changed = true
+			return
+		}
+		result[key] = original
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		for _, k := range v.MapKeys() {
+			redactEntry(k.String(), v.MapIndex(k))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name := f.Name
+			if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+			redactEntry(name, v.Field(i))
+		}
+	default:
+		return nil, false
+	}
+	if !changed {
+		return nil, false
+	}
+	return result, true
+}
+
+type redactFormatter struct {
+	formatter logrus.Formatter
+}
+
+// NewRedactFormatter wrap any logrus formatter (text, JSON ...)
+// so entries are redacted with the current policy before format
+func NewRedactFormatter(formatter logrus.Formatter) logrus.Formatter {
+	if r, ok := formatter.(*redactFormatter); ok {
+		return r
+	}
+	return &redactFormatter{formatter: formatter}
+}
+
+func (r *redactFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	policy := currentRedactionPolicy()
+	if policy == nil {
+		return r.formatter.Format(entry)
+	}
+	redacted := *entry
+	redacted.Data = policy.RedactFields(entry.Data)
+	redacted.Message = policy.RedactString(entry.Message)
+	return r.formatter.Format(&redacted)
+}
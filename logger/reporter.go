@@ -0,0 +1,196 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package logger
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// StackTracer is implemented by errors which captured
+// program counters at creation, the reporter prefer it over
+// the stack of logging call site
+type StackTracer interface {
+	StackTrace() []uintptr
+}
+
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// Event is an error captured from logger
+// which will be sent to the error reporting backend
+type Event struct {
+	Timestamp   time.Time
+	Level       logrus.Level
+	Message     string
+	ErrorType   string
+	Error       string
+	Stack       []Frame
+	RequestID   string
+	UserID      string
+	Method      string
+	ServiceID   string
+	Extra       map[string]interface{}
+	Fingerprint string
+}
+
+// Reporter deliver events to an error tracking backend,
+// Report is called while logger is locked so it must not block
+type Reporter interface {
+	Report(event *Event)
+}
+
+type reporterHook struct {
+	reporter Reporter
+	levels   []logrus.Level
+}
+
+// NewReporterHook create hook which capture entries at Error level
+// and above, register it with AddHook
+//
+// ```
+// reporter, _ := logger.NewSentryReporter(logger.SentryConfig{DSN: dsn})
+// logger.AddHook(logger.NewReporterHook(reporter))
+// ```
+func NewReporterHook(reporter Reporter) logrus.Hook {
+	return &reporterHook{
+		reporter: reporter,
+		levels:   []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel},
+	}
+}
+
+func (r *reporterHook) Levels() []logrus.Level {
+	return r.levels
+}
+
+func (r *reporterHook) Fire(entry *logrus.Entry) error {
+	r.reporter.Report(NewEvent(entry))
+	return nil
+}
+
+// Report send event with fields of this logger directly to reporter,
+// use it when the reporter is not registered by AddHook
+func (g Logger) Report(reporter Reporter, level logrus.Level, message string) {
+	var data = logrus.Fields{}
+	if entry, ok := g.FieldLogger.(*logrus.Entry); ok {
+		for k, v := range entry.Data {
+			data[k] = v
+		}
+	}
+	reporter.Report(NewEvent(&logrus.Entry{
+		Data:    data,
+		Time:    time.Now(),
+		Level:   level,
+		Message: message,
+	}))
+}
+
+// NewEvent build reporter event from log entry,
+// fields are redacted with the current RedactionPolicy
+func NewEvent(entry *logrus.Entry) *Event {
+	var (
+		data    = entry.Data
+		message = entry.Message
+	)
+	if policy := currentRedactionPolicy(); policy != nil {
+		data = policy.RedactFields(entry.Data)
+		message = policy.RedactString(message)
+	}
+
+	var event = &Event{
+		Timestamp: entry.Time,
+		Level:     entry.Level,
+		Message:   message,
+		Extra:     map[string]interface{}{},
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	for k, v := range data {
+		switch k {
+		case fieldRequestID:
+			event.RequestID = valueToString(v)
+		case fieldUserID:
+			event.UserID = valueToString(v)
+		case fieldURL:
+			event.Method = valueToString(v)
+		case fieldServiceID:
+			event.ServiceID = valueToString(v)
+		case fieldError:
+			event.Error = valueToString(v)
+		default:
+			event.Extra[k] = v
+		}
+	}
+
+	var pcs []uintptr
+	if err, ok := entry.Data[fieldError].(error); ok && err != nil {
+		event.ErrorType = fmt.Sprintf("%T", err)
+		if tracer, ok := err.(StackTracer); ok {
+			pcs = tracer.StackTrace()
+		}
+	}
+	if len(pcs) == 0 {
+		pcs = make([]uintptr, 64)
+		pcs = pcs[:runtime.Callers(2, pcs)]
+		event.Stack = callerFrames(pcs, true)
+	} else {
+		event.Stack = callerFrames(pcs, false)
+	}
+	event.Fingerprint = fingerprint(event)
+	return event
+}
+
+func callerFrames(pcs []uintptr, skipLogger bool) []Frame {
+	var (
+		result = make([]Frame, 0, len(pcs))
+		frames = runtime.CallersFrames(pcs)
+	)
+	for {
+		frame, more := frames.Next()
+		// skip frames inside logrus and logger until reach the caller
+		internal := strings.HasPrefix(frame.Function, "github.com/sirupsen/logrus") ||
+			(strings.HasPrefix(frame.Function, "github.com/octofoxio/foundation/logger.") && !strings.HasSuffix(frame.File, "_test.go"))
+		if !(skipLogger && len(result) == 0 && internal) {
+			result = append(result, Frame{
+				Function: frame.Function,
+				File:     frame.File,
+				Line:     frame.Line,
+			})
+		}
+		if !more {
+			break
+		}
+	}
+	return result
+}
+
+var fingerprintVariable = regexp.MustCompile(`[0-9a-fA-F-]{8,}|\d+`)
+
+// fingerprint group events by error type, message (without numbers and IDs)
+// and the top most frame
+func fingerprint(event *Event) string {
+	var h = sha1.New()
+	var message = event.Error
+	if message == "" {
+		message = event.Message
+	}
+	h.Write([]byte(event.ErrorType))
+	h.Write([]byte(fingerprintVariable.ReplaceAllString(message, "?")))
+	if len(event.Stack) > 0 {
+		h.Write([]byte(event.Stack[0].Function))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
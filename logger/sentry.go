@@ -0,0 +1,290 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package logger
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSentryBatchSize     = 20
+	defaultSentryFlushInterval = 5 * time.Second
+	defaultSentryQueueSize     = 1000
+)
+
+type SentryConfig struct {
+	// DSN in format of {scheme}://{key}[:{secret}]@{host}[/{path}]/{project}
+	DSN           string
+	Environment   string
+	Release       string
+	BatchSize     int
+	FlushInterval time.Duration
+	HTTPClient    *http.Client
+}
+
+// SentryReporter send events to Sentry compatible
+// store endpoint, events are buffered and sent in batch
+// by a background worker, duplicated events (same fingerprint)
+// in a batch are sent once with the number of occurrences
+type SentryReporter struct {
+	config     SentryConfig
+	endpoint   string
+	authHeader string
+	client     *http.Client
+
+	mux     *sync.Mutex
+	pending []*Event
+	flush   chan chan struct{}
+	done    chan struct{}
+	closed  bool
+}
+
+func NewSentryReporter(config SentryConfig) (*SentryReporter, error) {
+	dsn, err := url.Parse(config.DSN)
+	if err != nil {
+		return nil, err
+	}
+	if dsn.User == nil || dsn.User.Username() == "" {
+		return nil, fmt.Errorf("sentry DSN %s does not contain public key", config.DSN)
+	}
+	project := path.Base(dsn.Path)
+	if project == "" || project == "/" || project == "." {
+		return nil, fmt.Errorf("sentry DSN %s does not contain project ID", config.DSN)
+	}
+
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=foundation/1.0, sentry_key=%s", dsn.User.Username())
+	if secret, ok := dsn.User.Password(); ok {
+		auth = fmt.Sprintf("%s, sentry_secret=%s", auth, secret)
+	}
+	endpoint := url.URL{
+		Scheme: dsn.Scheme,
+		Host:   dsn.Host,
+		Path:   path.Join(path.Dir(dsn.Path), "api", project, "store") + "/",
+	}
+
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultSentryBatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaultSentryFlushInterval
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	var s = &SentryReporter{
+		config:     config,
+		endpoint:   endpoint.String(),
+		authHeader: auth,
+		client:     client,
+		mux:        &sync.Mutex{},
+		flush:      make(chan chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Report enqueue event, it never block the caller,
+// events are dropped when the queue is full
+func (s *SentryReporter) Report(event *Event) {
+	s.mux.Lock()
+	if s.closed || len(s.pending) >= defaultSentryQueueSize {
+		s.mux.Unlock()
+		return
+	}
+	s.pending = append(s.pending, event)
+	full := len(s.pending) >= s.config.BatchSize
+	s.mux.Unlock()
+
+	if full {
+		go s.Flush()
+	}
+}
+
+// Flush send every pending events and wait until finish
+func (s *SentryReporter) Flush() {
+	wait := make(chan struct{})
+	select {
+	case s.flush <- wait:
+		<-wait
+	case <-s.done:
+	}
+}
+
+// Close flush pending events and stop the background worker
+func (s *SentryReporter) Close() {
+	s.Flush()
+	s.mux.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+	s.mux.Unlock()
+}
+
+func (s *SentryReporter) run() {
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.send()
+		case wait := <-s.flush:
+			s.send()
+			close(wait)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *SentryReporter) send() {
+	s.mux.Lock()
+	var events = s.pending
+	s.pending = nil
+	s.mux.Unlock()
+
+	var (
+		occurrences = map[string]int{}
+		batch       = make([]*Event, 0, len(events))
+	)
+	for _, e := range events {
+		if occurrences[e.Fingerprint] == 0 {
+			batch = append(batch, e)
+		}
+		occurrences[e.Fingerprint]++
+	}
+	for _, e := range batch {
+		if err := s.post(s.payload(e, occurrences[e.Fingerprint])); err != nil {
+			// do not use logger here, an error log will be reported again
+			fmt.Fprintf(os.Stderr, "SentryReporter: cannot send event %s\n", err.Error())
+		}
+	}
+}
+
+func (s *SentryReporter) post(payload map[string]interface{}) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.authHeader)
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("sentry responded with status %d", res.StatusCode)
+	}
+	return nil
+}
+
+func sentryLevel(level logrus.Level) string {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return "fatal"
+	case logrus.ErrorLevel:
+		return "error"
+	case logrus.WarnLevel:
+		return "warning"
+	case logrus.InfoLevel:
+		return "info"
+	}
+	return "debug"
+}
+
+func (s *SentryReporter) payload(e *Event, occurrences int) map[string]interface{} {
+	var eventID = make([]byte, 16)
+	_, _ = rand.Read(eventID)
+
+	var tags = map[string]string{}
+	if e.RequestID != "" {
+		tags["request_id"] = e.RequestID
+	}
+	if e.Method != "" {
+		tags["method"] = e.Method
+	}
+	if e.ServiceID != "" {
+		tags["service_id"] = e.ServiceID
+	}
+
+	var extra = map[string]interface{}{}
+	for k, v := range e.Extra {
+		extra[k] = valueToString(v)
+	}
+	if occurrences > 1 {
+		extra["occurrences"] = occurrences
+	}
+
+	// sentry expect frames ordered from oldest to newest
+	var frames = make([]map[string]interface{}, 0, len(e.Stack))
+	for i := len(e.Stack) - 1; i >= 0; i-- {
+		f := e.Stack[i]
+		frames = append(frames, map[string]interface{}{
+			"function": f.Function,
+			"filename": f.File,
+			"lineno":   f.Line,
+			"in_app":   !strings.HasPrefix(f.Function, "runtime.") && !strings.HasPrefix(f.Function, "testing."),
+		})
+	}
+
+	var exceptionType, exceptionValue = e.ErrorType, e.Error
+	if exceptionType == "" {
+		exceptionType = "error"
+	}
+	if exceptionValue == "" {
+		exceptionValue = e.Message
+	}
+
+	var payload = map[string]interface{}{
+		"event_id":    hex.EncodeToString(eventID),
+		"timestamp":   e.Timestamp.UTC().Format("2006-01-02T15:04:05"),
+		"level":       sentryLevel(e.Level),
+		"logger":      e.ServiceID,
+		"platform":    "go",
+		"message":     e.Message,
+		"fingerprint": []string{e.Fingerprint},
+		"tags":        tags,
+		"extra":       extra,
+		"exception": map[string]interface{}{
+			"values": []map[string]interface{}{
+				{
+					"type":       exceptionType,
+					"value":      exceptionValue,
+					"stacktrace": map[string]interface{}{"frames": frames},
+				},
+			},
+		},
+	}
+	if e.UserID != "" {
+		payload["user"] = map[string]string{"id": e.UserID}
+	}
+	if s.config.Environment != "" {
+		payload["environment"] = s.config.Environment
+	}
+	if s.config.Release != "" {
+		payload["release"] = s.config.Release
+	}
+	return payload
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type sentryReceiver struct {
+	mux     sync.Mutex
+	auth    []string
+	events  []map[string]interface{}
+	request []string
+}
+
+func (s *sentryReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, _ := ioutil.ReadAll(r.Body)
+	var event map[string]interface{}
+	_ = json.Unmarshal(b, &event)
+	s.mux.Lock()
+	s.auth = append(s.auth, r.Header.Get("X-Sentry-Auth"))
+	s.request = append(s.request, r.URL.Path)
+	s.events = append(s.events, event)
+	s.mux.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestSentryReporter(t *testing.T) {
+	receiver := &sentryReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	reporter, err := NewSentryReporter(SentryConfig{
+		DSN:           fmt.Sprintf("http://public:secret@%s/sentry/42", server.Listener.Addr().String()),
+		Environment:   "test",
+		FlushInterval: time.Hour,
+	})
+	assert.NoError(t, err)
+	defer reporter.Close()
+
+	AddHook(NewReporterHook(reporter))
+	defer func() {
+		hooksMux.Lock()
+		hooks = logrus.LevelHooks{}
+		hooksMux.Unlock()
+	}()
+
+	log := New("sentry").SetOutput(bytes.NewBuffer(nil)).
+		WithRequestID("REQ-1").
+		WithUserID("mario").
+		WithURL("grpc", "/user.Get")
+	for i := 0; i < 3; i++ {
+		log.WithError(fmt.Errorf("order %d not found", i)).Error("cannot get order")
+	}
+	log.WithError(errors.New("database is gone")).WithField("password", "peach").Error("cannot connect")
+	log.Info("info must not be reported")
+	log.Warn("warn must not be reported")
+	reporter.Flush()
+
+	receiver.mux.Lock()
+	defer receiver.mux.Unlock()
+	assert.Len(t, receiver.events, 2, "duplicated errors should be grouped")
+	assert.Equal(t, "/sentry/api/42/store/", receiver.request[0])
+	assert.Contains(t, receiver.auth[0], "sentry_key=public")
+	assert.Contains(t, receiver.auth[0], "sentry_secret=secret")
+
+	grouped := receiver.events[0]
+	assert.Equal(t, "error", grouped["level"])
+	assert.Equal(t, "test", grouped["environment"])
+	assert.Equal(t, "REQ-1", grouped["tags"].(map[string]interface{})["request_id"])
+	assert.Equal(t, "grpc /user.Get", grouped["tags"].(map[string]interface{})["method"])
+	assert.Equal(t, "mario", grouped["user"].(map[string]interface{})["id"])
+	assert.EqualValues(t, 3, grouped["extra"].(map[string]interface{})["occurrences"])
+
+	exception := grouped["exception"].(map[string]interface{})["values"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "order 0 not found", exception["value"])
+	frames := exception["stacktrace"].(map[string]interface{})["frames"].([]interface{})
+	assert.NotEmpty(t, frames)
+	top := frames[len(frames)-1].(map[string]interface{})
+	assert.Equal(t, "github.com/octofoxio/foundation/logger.TestSentryReporter", top["function"])
+
+	other := receiver.events[1]
+	assert.Equal(t, defaultRedactionMask, other["extra"].(map[string]interface{})["password"])
+}
+
+func TestNewSentryReporterInvalidDSN(t *testing.T) {
+	_, err := NewSentryReporter(SentryConfig{DSN: "http://localhost/1"})
+	assert.Error(t, err)
+	_, err = NewSentryReporter(SentryConfig{DSN: "http://key@localhost/"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package foundation
+
+import (
+	"context"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/octofoxio/foundation/logger"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RecoverPanic convert value recovered from panic to *errors.Error
+// and log it with the request context logger, the error is sent
+// to reporters as well (reporters registered by logger.AddHook
+// already receive the log so they should not be passed here)
+func RecoverPanic(ctx context.Context, r interface{}, reporters ...logger.Reporter) *errors.Error {
+	var err = errors.FromPanic(r)
+	var log = recoveryLogger(ctx).WithError(err)
+	log.Errorf("panic recovered: %v", r)
+	for _, reporter := range reporters {
+		log.Report(reporter, logrus.PanicLevel, "panic recovered")
+	}
+	return err
+}
+
+// recoveryLogger return logger from context, recovery interceptor
+// is usually the first one so the context may not contain logger yet
+func recoveryLogger(ctx context.Context) *logger.Logger {
+	if log, ok := ctx.Value(FoundationLoggerContextKey).(*logger.Logger); ok && log != nil {
+		return log
+	}
+	var log = logger.New("foundation")
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if requestIDs := md.Get(GRPC_METADATA_REQUEST_ID_KEY); len(requestIDs) > 0 {
+			log = log.WithRequestID(requestIDs[0])
+		}
+	}
+	return log
+}
+
+// PanicRecoveryInterceptor convert panic in handler (and interceptors after it)
+// to Internal error, *errors.Error and foundationerrorv2.Error keep their code
+func PanicRecoveryInterceptor(reporters ...logger.Reporter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				resp = nil
+				err = errors.ToGRPCError(RecoverPanic(withMethodLogger(ctx, info.FullMethod), r, reporters...)).Err()
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func PanicRecoveryStreamInterceptor(reporters ...logger.Reporter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = errors.ToGRPCError(RecoverPanic(withMethodLogger(stream.Context(), info.FullMethod), r, reporters...)).Err()
+			}
+		}()
+		return handler(srv, stream)
+	}
+}
+
+func withMethodLogger(ctx context.Context, method string) context.Context {
+	return AppendLoggerToContext(ctx, recoveryLogger(ctx).WithServiceInfo(method))
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package foundation
+
+import (
+	"bytes"
+	"context"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/octofoxio/foundation/logger"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"sync"
+	"testing"
+)
+
+type recordReporter struct {
+	mux    sync.Mutex
+	events []*logger.Event
+}
+
+func (r *recordReporter) Report(event *logger.Event) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.events = append(r.events, event)
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestPanicRecoveryInterceptor(t *testing.T) {
+	var (
+		reporter    = &recordReporter{}
+		interceptor = PanicRecoveryInterceptor(reporter)
+		info        = &grpc.UnaryServerInfo{FullMethod: "/test.Test/Ping"}
+		output      = &bytes.Buffer{}
+	)
+	ctx := AppendLoggerToContext(context.Background(), logger.New("test").SetOutput(output).WithRequestID("request-1"))
+
+	t.Run("nil pointer", func(t *testing.T) {
+		resp, err := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			var input *PingInput
+			return input.Greeting, nil
+		})
+		assert.Nil(t, resp)
+		st, _ := status.FromError(err)
+		assert.Equal(t, codes.Internal, st.Code())
+		assert.Contains(t, st.Message(), "nil pointer")
+		assert.Contains(t, output.String(), "request-1")
+		assert.Contains(t, output.String(), "panic recovered")
+
+		assert.Len(t, reporter.events, 1)
+		event := reporter.events[0]
+		assert.Equal(t, "request-1", event.RequestID)
+		assert.NotEmpty(t, event.Stack)
+	})
+
+	t.Run("foundation error keep its code", func(t *testing.T) {
+		_, err := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic(errors.New(errors.ErrorTypeNotfound, "not found"))
+		})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("unknown value never re-panic", func(t *testing.T) {
+		_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic(struct{ X int }{1})
+		})
+		assert.Equal(t, codes.Internal, status.Code(err))
+	})
+}
+
+func TestPanicRecoveryStreamInterceptor(t *testing.T) {
+	var interceptor = PanicRecoveryStreamInterceptor()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(GRPC_METADATA_REQUEST_ID_KEY, "request-2"))
+	err := interceptor(nil, &testServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/test.Test/Watch"},
+		func(srv interface{}, stream grpc.ServerStream) error {
+			panic("stream closed")
+		})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "stream closed")
+}
@@ -5,14 +5,15 @@
 package foundation
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/octofoxio/foundation/errors"
 	"github.com/octofoxio/foundation/logger"
 	"io"
@@ -38,6 +39,15 @@ type FileStorage interface {
 	Exists(key string) (exists bool, err error)
 }
 
+// StreamFileStorage is FileStorage which can store object while reading
+// from reader, so the size does not need to be known and the object
+// is not buffered in memory (e.g. uploaded file of HTTP request)
+type StreamFileStorage interface {
+	FileStorage
+	PutObjectFromReader(key string, reader io.Reader) (err error)
+	PutPublicObjectFromReader(key string, reader io.Reader) (err error)
+}
+
 type S3FileStorage struct {
 	BucketName string
 	awsConfig  *aws.Config
@@ -53,8 +63,18 @@ func (s *S3FileStorage) s3() (s3Client *s3.S3, err error) {
 	return
 }
 
+// background is context of methods without context,
+// they log through logger of the storage
+func (s *S3FileStorage) background() context.Context {
+	return AppendLoggerToContext(context.Background(), s.log)
+}
+
 func (s *S3FileStorage) GetJSONObject(key string, data interface{}) (err error) {
-	file, err := s.GetObject(key)
+	return s.GetJSONObjectWithContext(s.background(), key, data)
+}
+
+func (s *S3FileStorage) GetJSONObjectWithContext(ctx context.Context, key string, data interface{}) (err error) {
+	file, err := s.GetObjectWithContext(ctx, key)
 	if err != nil {
 		return err
 	}
@@ -72,7 +92,12 @@ func (s *S3FileStorage) getURLByPath(bucketName, region, key string) (string, er
 	urlParse.Path = path.Join(key)
 	return urlParse.String(), nil
 }
+
 func (s *S3FileStorage) GetObjectURL(key string) (objectURL string, err error) {
+	return s.GetObjectURLWithContext(s.background(), key)
+}
+
+func (s *S3FileStorage) GetObjectURLWithContext(ctx context.Context, key string) (objectURL string, err error) {
 	s3Client, err := s.s3()
 	if err != nil {
 		return
@@ -85,25 +110,26 @@ func (s *S3FileStorage) GetObjectURL(key string) (objectURL string, err error) {
 	}
 
 	// validate if object exists
-	_, err = s3Client.GetObjectAcl(&s3.GetObjectAclInput{
+	_, err = s3Client.GetObjectAclWithContext(ctx, &s3.GetObjectAclInput{
 		Bucket: aws.String(s.BucketName),
 		Key:    aws.String(key),
 	})
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case s3.ErrCodeNoSuchKey:
-				return objectURL, errors.New(errors.ErrorTypeNotfound, fmt.Sprintf("file %s not found", key))
-			default:
-				return objectURL, err
-			}
-		}
-		return objectURL, err
+		return objectURL, s3NotFound(err, key)
 	}
 	return objectURL, err
 }
 
 func (s *S3FileStorage) GetObjectPreSignURL(key string) (url string, err error) {
+	return s.GetObjectPreSignURLWithContext(s.background(), key)
+}
+
+// GetObjectPreSignURLWithContext sign URL locally,
+// ctx is only checked before signing
+func (s *S3FileStorage) GetObjectPreSignURLWithContext(ctx context.Context, key string) (url string, err error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 	s3Client, err := s.s3()
 	if err != nil {
 		return
@@ -117,22 +143,35 @@ func (s *S3FileStorage) GetObjectPreSignURL(key string) (url string, err error)
 }
 
 func (s *S3FileStorage) RemoveObject(key string) (err error) {
+	return s.RemoveObjectWithContext(s.background(), key)
+}
+
+func (s *S3FileStorage) RemoveObjectWithContext(ctx context.Context, key string) (err error) {
 	s3Client, err := s.s3()
 	if err != nil {
 		return err
 	}
-	output, err := s3Client.DeleteObject(&s3.DeleteObjectInput{
+	output, err := s3Client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
 		Key:    aws.String(key),
 		Bucket: aws.String(s.BucketName),
 	})
 	if err != nil {
 		return err
 	}
-	s.log.Printf("Remove fileInfo from storage %s", output.String())
+	GetLoggerFromContext(ctx).Printf("Remove fileInfo from storage %s", output.String())
 	return nil
 }
 
 func (s *S3FileStorage) GetPreSignUploadURL(key string, size int64) (url string, err error) {
+	return s.GetPreSignUploadURLWithContext(s.background(), key, size)
+}
+
+// GetPreSignUploadURLWithContext sign URL locally,
+// ctx is only checked before signing
+func (s *S3FileStorage) GetPreSignUploadURLWithContext(ctx context.Context, key string, size int64) (url string, err error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 	s3Client, err := s.s3()
 	if err != nil {
 		return
@@ -151,94 +190,171 @@ func (s *S3FileStorage) GetPreSignUploadURL(key string, size int64) (url string,
 // GetObjectReader caller should manually close io reader
 // for prevent memory leaking
 func (s *S3FileStorage) GetObjectReader(key string) (reader io.ReadCloser, err error) {
+	return s.GetObjectReaderWithContext(s.background(), key)
+}
+
+// GetObjectReaderWithContext caller should manually close io reader,
+// reading is canceled when ctx is done
+func (s *S3FileStorage) GetObjectReaderWithContext(ctx context.Context, key string) (reader io.ReadCloser, err error) {
 	s3Client, err := s.s3()
 	if err != nil {
 		return
 	}
-	output, err := s3Client.GetObject(&s3.GetObjectInput{
+	output, err := s3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
 		Key:    aws.String(key),
 		Bucket: aws.String(s.BucketName),
 	})
 	if err != nil {
-		return
+		return nil, s3NotFound(err, key)
 	}
 	reader = output.Body
 	return
 }
 
 func (s *S3FileStorage) PutObject(key string, data []byte) (err error) {
-	r := bytes.NewReader(data)
-	return s.PutObjectFromReadSeeker(key, r)
+	return s.PutObjectWithContext(s.background(), key, data)
+}
+
+func (s *S3FileStorage) PutObjectWithContext(ctx context.Context, key string, data []byte) (err error) {
+	return s.PutObjectFromReadSeekerWithContext(ctx, key, bytes.NewReader(data))
 }
 
 func (s *S3FileStorage) PutObjectFromReadSeeker(key string, reader io.ReadSeeker) (err error) {
-	s3Client, err := s.s3()
-	if err != nil {
-		return err
-	}
+	return s.PutObjectFromReadSeekerWithContext(s.background(), key, reader)
+}
+
+func (s *S3FileStorage) PutObjectFromReadSeekerWithContext(ctx context.Context, key string, reader io.ReadSeeker) (err error) {
+	return s.putObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.BucketName),
+		Key:    aws.String(key),
+		Body:   reader,
+	})
+}
 
-	putObjectOutput, err := s3Client.PutObject(&s3.PutObjectInput{
+// PutObjectFromReader upload object in parts by s3manager,
+// only a part is kept in memory at a time
+func (s *S3FileStorage) PutObjectFromReader(key string, reader io.Reader) (err error) {
+	return s.PutObjectFromReaderWithContext(s.background(), key, reader)
+}
+
+func (s *S3FileStorage) PutObjectFromReaderWithContext(ctx context.Context, key string, reader io.Reader) (err error) {
+	return s.upload(ctx, &s3manager.UploadInput{
 		Bucket: aws.String(s.BucketName),
 		Key:    aws.String(key),
 		Body:   reader,
 	})
+}
+
+func (s *S3FileStorage) PutPublicObjectFromReader(key string, reader io.Reader) (err error) {
+	return s.PutPublicObjectFromReaderWithContext(s.background(), key, reader)
+}
+
+func (s *S3FileStorage) PutPublicObjectFromReaderWithContext(ctx context.Context, key string, reader io.Reader) (err error) {
+	return s.upload(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(s.BucketName),
+		Key:    aws.String(key),
+		Body:   reader,
+		ACL:    aws.String("public-read"),
+	})
+}
+
+func (s *S3FileStorage) upload(ctx context.Context, input *s3manager.UploadInput) error {
+	awsSession, err := session.NewSession(s.awsConfig)
 	if err != nil {
 		return err
 	}
-	s.log.Printf("S3FileStorage: fileInfo upload complete, %s", *putObjectOutput.ETag)
-	return
+	output, err := s3manager.NewUploader(awsSession).UploadWithContext(ctx, input)
+	if err != nil {
+		return err
+	}
+	GetLoggerFromContext(ctx).Printf("S3FileStorage: fileInfo upload complete, %s", output.Location)
+	return nil
 }
 
 func (s *S3FileStorage) PutPublicObject(key string, data []byte) (err error) {
-	r := bytes.NewReader(data)
-	return s.PutPublicObjectFromReadSeeker(key, r)
+	return s.PutPublicObjectWithContext(s.background(), key, data)
+}
+
+func (s *S3FileStorage) PutPublicObjectWithContext(ctx context.Context, key string, data []byte) (err error) {
+	return s.PutPublicObjectFromReadSeekerWithContext(ctx, key, bytes.NewReader(data))
 }
 
 func (s *S3FileStorage) PutPublicObjectFromReadSeeker(key string, r io.ReadSeeker) (err error) {
-	s3Client, err := s.s3()
-	if err != nil {
-		return err
-	}
+	return s.PutPublicObjectFromReadSeekerWithContext(s.background(), key, r)
+}
 
-	putObjectOutput, err := s3Client.PutObject(&s3.PutObjectInput{
+func (s *S3FileStorage) PutPublicObjectFromReadSeekerWithContext(ctx context.Context, key string, r io.ReadSeeker) (err error) {
+	return s.putObject(ctx, &s3.PutObjectInput{
 		Bucket: aws.String(s.BucketName),
 		Key:    aws.String(key),
 		Body:   r,
 		ACL:    aws.String("public-read"),
 	})
+}
+
+func (s *S3FileStorage) putObject(ctx context.Context, input *s3.PutObjectInput) error {
+	s3Client, err := s.s3()
 	if err != nil {
 		return err
 	}
-	s.log.Printf("S3FileStorage: fileInfo upload complete, %s", *putObjectOutput.ETag)
-	return
+	putObjectOutput, err := s3Client.PutObjectWithContext(ctx, input)
+	if err != nil {
+		return err
+	}
+	GetLoggerFromContext(ctx).Printf("S3FileStorage: fileInfo upload complete, %s", aws.StringValue(putObjectOutput.ETag))
+	return nil
 }
 
 func (s *S3FileStorage) GetObject(key string) (result []byte, err error) {
-	output, err := s.GetObjectReader(key) // this method get reader from s3 API but not close
-	defer func() {
-		// skip close body if output == nil
-		if output == nil {
-			return
-		}
-		err = output.Close() // close response reader after read every bytes into memory
-		if err != nil {
-			fmt.Printf("CANNOT CLOSE OBJECT READER POSSIBLE TO HAVE SOME MEMORY LEAK %s \n", key)
-			fmt.Println(err.Error())
-		}
-	}()
+	return s.GetObjectWithContext(s.background(), key)
+}
+
+func (s *S3FileStorage) GetObjectWithContext(ctx context.Context, key string) (result []byte, err error) {
+	output, err := s.GetObjectReaderWithContext(ctx, key) // this method get reader from s3 API but not close
 	if err != nil {
 		return result, err
 	}
+	defer func() {
+		// close response reader after read every bytes into memory
+		if closeErr := output.Close(); closeErr != nil {
+			GetLoggerFromContext(ctx).WithError(closeErr).Errorf("cannot close object reader of %s, possible to have some memory leak", key)
+		}
+	}()
 	// get all result to byte array
-	result, err = ioutil.ReadAll(output)
-	return
+	return ioutil.ReadAll(output)
 }
 
 func (s *S3FileStorage) Exists(key string) (exists bool, err error) {
-	// not yet implements
+	return s.ExistsWithContext(s.background(), key)
+}
+
+func (s *S3FileStorage) ExistsWithContext(ctx context.Context, key string) (exists bool, err error) {
+	s3Client, err := s.s3()
+	if err != nil {
+		return false, err
+	}
+	_, err = s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		// HEAD response has no body, so there is only status code
+		if aerr, ok := err.(awserr.RequestFailure); ok && aerr.StatusCode() == 404 {
+			return false, nil
+		}
+		return false, err
+	}
 	return true, nil
 }
 
+// s3NotFound convert no such key error into not found error
+func s3NotFound(err error, key string) error {
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return errors.New(errors.ErrorTypeNotfound, fmt.Sprintf("file %s not found", key))
+	}
+	return err
+}
+
 func NewS3FileStorage(bucketName string, awsConfig *aws.Config) *S3FileStorage {
 	return &S3FileStorage{BucketName: bucketName, awsConfig: awsConfig, log: logger.New("S3FileStorage")}
 }
@@ -247,20 +363,68 @@ type LocalFileStorage struct {
 	Path string
 }
 
+// background is context of methods without context
+func (l *LocalFileStorage) background() context.Context {
+	return AppendLoggerToContext(context.Background(), logger.New("LocalFileStorage"))
+}
+
 func (l *LocalFileStorage) PutObjectFromReadSeeker(key string, reader io.ReadSeeker) (err error) {
-	r, err := ioutil.ReadAll(reader)
+	return l.PutObjectFromReaderWithContext(l.background(), key, reader)
+}
+
+func (l *LocalFileStorage) PutObjectFromReadSeekerWithContext(ctx context.Context, key string, reader io.ReadSeeker) (err error) {
+	return l.PutObjectFromReaderWithContext(ctx, key, reader)
+}
+
+func (l *LocalFileStorage) PutPublicObjectFromReadSeeker(key string, reader io.ReadSeeker) (err error) {
+	return l.PutObjectFromReaderWithContext(l.background(), key, reader)
+}
+
+func (l *LocalFileStorage) PutPublicObjectFromReadSeekerWithContext(ctx context.Context, key string, reader io.ReadSeeker) (err error) {
+	return l.PutObjectFromReaderWithContext(ctx, key, reader)
+}
+
+// PutObjectFromReader copy reader into the file, partial
+// file is removed when reader return error
+func (l *LocalFileStorage) PutObjectFromReader(key string, reader io.Reader) (err error) {
+	return l.PutObjectFromReaderWithContext(l.background(), key, reader)
+}
+
+// PutObjectFromReaderWithContext stop copying when ctx is done
+func (l *LocalFileStorage) PutObjectFromReaderWithContext(ctx context.Context, key string, reader io.Reader) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	filePath := path.Join(l.Path, key)
+	if err := os.MkdirAll(path.Dir(filePath), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.Create(filePath)
 	if err != nil {
 		return err
 	}
-	return l.PutObject(key, r)
+	if _, err = io.Copy(f, &contextReader{ctx: ctx, Reader: reader}); err != nil {
+		_ = f.Close()
+		_ = os.Remove(filePath)
+		return err
+	}
+	return f.Close()
 }
 
-func (l *LocalFileStorage) PutPublicObjectFromReadSeeker(key string, reader io.ReadSeeker) (err error) {
-	return l.PutObjectFromReadSeeker(key, reader)
+func (l *LocalFileStorage) PutPublicObjectFromReader(key string, reader io.Reader) (err error) {
+	return l.PutObjectFromReaderWithContext(l.background(), key, reader)
+}
+
+func (l *LocalFileStorage) PutPublicObjectFromReaderWithContext(ctx context.Context, key string, reader io.Reader) (err error) {
+	return l.PutObjectFromReaderWithContext(ctx, key, reader)
 }
 
 func (l *LocalFileStorage) GetJSONObject(key string, data interface{}) (err error) {
-	file, err := l.GetObject(key)
+	return l.GetJSONObjectWithContext(l.background(), key, data)
+}
+
+func (l *LocalFileStorage) GetJSONObjectWithContext(ctx context.Context, key string, data interface{}) (err error) {
+	file, err := l.GetObjectWithContext(ctx, key)
 	if err != nil {
 		return err
 	}
@@ -273,11 +437,18 @@ func (l *LocalFileStorage) GetJSONObject(key string, data interface{}) (err erro
 // GetObjectURL require to implement path for it (local only)
 // see dev_api on local storage URL support
 func (l *LocalFileStorage) GetObjectURL(key string) (url string, err error) {
+	return l.GetObjectURLWithContext(l.background(), key)
+}
+
+func (l *LocalFileStorage) GetObjectURLWithContext(ctx context.Context, key string) (url string, err error) {
 	filePath := path.Join(l.Path, key)
 	pathURL := url2.URL{
 		Path:   filePath,
 		Scheme: "file",
 	}
+	if err := ctx.Err(); err != nil {
+		return pathURL.String(), err
+	}
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		return pathURL.String(), err
 	}
@@ -285,11 +456,22 @@ func (l *LocalFileStorage) GetObjectURL(key string) (url string, err error) {
 }
 
 func (l *LocalFileStorage) GetObjectPreSignURL(key string) (url string, err error) {
-	return l.GetObjectURL(key)
+	return l.GetObjectURLWithContext(l.background(), key)
+}
+
+func (l *LocalFileStorage) GetObjectPreSignURLWithContext(ctx context.Context, key string) (url string, err error) {
+	return l.GetObjectURLWithContext(ctx, key)
 }
 
 func (l *LocalFileStorage) RemoveObject(key string) (err error) {
-	fmt.Println(key)
+	return l.RemoveObjectWithContext(l.background(), key)
+}
+
+func (l *LocalFileStorage) RemoveObjectWithContext(ctx context.Context, key string) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	GetLoggerFromContext(ctx).Printf("Remove file %s from storage", key)
 	if _, err := os.Stat(path.Join(l.Path, key)); os.IsNotExist(err) {
 		return nil
 	} else {
@@ -307,12 +489,26 @@ func NewLocalFileStorage(path string) *LocalFileStorage {
 		Path: path,
 	}
 }
+
+// GetPreSignUploadURL is not supported by local storage,
+// upload through http.UploadHandler instead
 func (l *LocalFileStorage) GetPreSignUploadURL(key string, size int64) (url string, err error) {
-	panic("implement me")
+	return l.GetPreSignUploadURLWithContext(l.background(), key, size)
+}
+
+func (l *LocalFileStorage) GetPreSignUploadURLWithContext(ctx context.Context, key string, size int64) (url string, err error) {
+	return "", errors.New(errors.ErrorTypeNotImplemented, "local storage does not support pre-signed upload URL")
 }
 
 func (l *LocalFileStorage) GetObjectReader(key string) (result io.ReadCloser, err error) {
-	f, err := os.Open(key)
+	return l.GetObjectReaderWithContext(l.background(), key)
+}
+
+func (l *LocalFileStorage) GetObjectReaderWithContext(ctx context.Context, key string) (result io.ReadCloser, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path.Join(l.Path, key))
 	if err != nil {
 		return nil, err
 	}
@@ -320,45 +516,40 @@ func (l *LocalFileStorage) GetObjectReader(key string) (result io.ReadCloser, er
 }
 
 func (l *LocalFileStorage) PutObject(key string, data []byte) (err error) {
-	filePath := path.Join(l.Path, key)
-	{
-		if _, e := os.Stat(path.Dir(filePath)); os.IsNotExist(e) {
-			err = os.MkdirAll(path.Dir(filePath), os.ModePerm)
-			if err != nil {
-				return err
-			}
-		}
-		f, err := os.Create(filePath)
-		if err != nil {
-			return err
-		}
-		w := bufio.NewWriter(f)
-		_, err = w.Write(data)
-		if err != nil {
-			return err
-		}
-		err = w.Flush()
-		if err != nil {
-			return err
-		}
-	}
+	return l.PutObjectWithContext(l.background(), key, data)
+}
 
-	return err
+func (l *LocalFileStorage) PutObjectWithContext(ctx context.Context, key string, data []byte) (err error) {
+	return l.PutObjectFromReaderWithContext(ctx, key, bytes.NewReader(data))
 }
 
 func (l *LocalFileStorage) PutPublicObject(key string, data []byte) (err error) {
-	return l.PutObject(key, data)
+	return l.PutObjectWithContext(l.background(), key, data)
+}
+
+func (l *LocalFileStorage) PutPublicObjectWithContext(ctx context.Context, key string, data []byte) (err error) {
+	return l.PutObjectWithContext(ctx, key, data)
 }
 
 func (l *LocalFileStorage) GetObject(key string) (result []byte, err error) {
-	result, err = ioutil.ReadFile(path.Join(l.Path, key))
-	if err != nil {
-		return
+	return l.GetObjectWithContext(l.background(), key)
+}
+
+func (l *LocalFileStorage) GetObjectWithContext(ctx context.Context, key string) (result []byte, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	return
+	return ioutil.ReadFile(path.Join(l.Path, key))
 }
 
 func (l *LocalFileStorage) Exists(key string) (exists bool, err error) {
+	return l.ExistsWithContext(l.background(), key)
+}
+
+func (l *LocalFileStorage) ExistsWithContext(ctx context.Context, key string) (exists bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
 	info, err := os.Stat(path.Join(l.Path, key))
 	if os.IsNotExist(err) {
 		return false, nil
@@ -0,0 +1,224 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package foundation
+
+import (
+	"context"
+	"io"
+)
+
+// FileStorageWithContext is FileStorage which every method take context,
+// calls are canceled with ctx (e.g. when the RPC is canceled) and
+// storage log through logger of ctx, so logs have request ID.
+// S3FileStorage and LocalFileStorage implement both interfaces,
+// methods without context use context.Background()
+type FileStorageWithContext interface {
+	GetObjectWithContext(ctx context.Context, key string) (result []byte, err error)
+	PutObjectWithContext(ctx context.Context, key string, data []byte) (err error)
+	PutObjectFromReadSeekerWithContext(ctx context.Context, key string, reader io.ReadSeeker) (err error)
+	PutPublicObjectWithContext(ctx context.Context, key string, data []byte) (err error)
+	PutPublicObjectFromReadSeekerWithContext(ctx context.Context, key string, reader io.ReadSeeker) (err error)
+	RemoveObjectWithContext(ctx context.Context, key string) (err error)
+	GetJSONObjectWithContext(ctx context.Context, key string, data interface{}) (err error)
+	GetObjectURLWithContext(ctx context.Context, key string) (url string, err error)
+	GetObjectPreSignURLWithContext(ctx context.Context, key string) (url string, err error)
+	GetObjectReaderWithContext(ctx context.Context, key string) (result io.ReadCloser, err error)
+	GetPreSignUploadURLWithContext(ctx context.Context, key string, size int64) (url string, err error)
+	ExistsWithContext(ctx context.Context, key string) (exists bool, err error)
+}
+
+// StreamFileStorageWithContext is StreamFileStorage which take context
+type StreamFileStorageWithContext interface {
+	FileStorageWithContext
+	PutObjectFromReaderWithContext(ctx context.Context, key string, reader io.Reader) (err error)
+	PutPublicObjectFromReaderWithContext(ctx context.Context, key string, reader io.Reader) (err error)
+}
+
+// WithContextFileStorage adapt FileStorage to FileStorageWithContext,
+// storage which already implement it is returned as-is. Calls of
+// adapted storage cannot be canceled once they are started,
+// ctx is checked before each call
+//
+// ```
+// var storage = foundation.WithContextFileStorage(legacyStorage)
+// b, err := storage.GetObjectWithContext(ctx, "avatars/1.png")
+// ```
+func WithContextFileStorage(storage FileStorage) FileStorageWithContext {
+	if s, ok := storage.(FileStorageWithContext); ok {
+		return s
+	}
+	return &fileStorageWithContext{storage: storage}
+}
+
+// WithoutContextFileStorage adapt FileStorageWithContext to FileStorage,
+// so it can be used by code which does not have context yet
+func WithoutContextFileStorage(storage FileStorageWithContext) FileStorage {
+	if s, ok := storage.(FileStorage); ok {
+		return s
+	}
+	return &fileStorageWithoutContext{storage: storage}
+}
+
+type fileStorageWithContext struct {
+	storage FileStorage
+}
+
+func (f *fileStorageWithContext) GetObjectWithContext(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return f.storage.GetObject(key)
+}
+
+func (f *fileStorageWithContext) PutObjectWithContext(ctx context.Context, key string, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.storage.PutObject(key, data)
+}
+
+func (f *fileStorageWithContext) PutObjectFromReadSeekerWithContext(ctx context.Context, key string, reader io.ReadSeeker) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.storage.PutObjectFromReadSeeker(key, reader)
+}
+
+func (f *fileStorageWithContext) PutPublicObjectWithContext(ctx context.Context, key string, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.storage.PutPublicObject(key, data)
+}
+
+func (f *fileStorageWithContext) PutPublicObjectFromReadSeekerWithContext(ctx context.Context, key string, reader io.ReadSeeker) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.storage.PutPublicObjectFromReadSeeker(key, reader)
+}
+
+func (f *fileStorageWithContext) RemoveObjectWithContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.storage.RemoveObject(key)
+}
+
+func (f *fileStorageWithContext) GetJSONObjectWithContext(ctx context.Context, key string, data interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.storage.GetJSONObject(key, data)
+}
+
+func (f *fileStorageWithContext) GetObjectURLWithContext(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return f.storage.GetObjectURL(key)
+}
+
+func (f *fileStorageWithContext) GetObjectPreSignURLWithContext(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return f.storage.GetObjectPreSignURL(key)
+}
+
+func (f *fileStorageWithContext) GetObjectReaderWithContext(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	reader, err := f.storage.GetObjectReader(key)
+	if err != nil {
+		return nil, err
+	}
+	return &contextReadCloser{contextReader: contextReader{ctx: ctx, Reader: reader}, Closer: reader}, nil
+}
+
+func (f *fileStorageWithContext) GetPreSignUploadURLWithContext(ctx context.Context, key string, size int64) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return f.storage.GetPreSignUploadURL(key, size)
+}
+
+func (f *fileStorageWithContext) ExistsWithContext(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return f.storage.Exists(key)
+}
+
+type fileStorageWithoutContext struct {
+	storage FileStorageWithContext
+}
+
+func (f *fileStorageWithoutContext) GetObject(key string) ([]byte, error) {
+	return f.storage.GetObjectWithContext(NewContext(context.Background()), key)
+}
+
+func (f *fileStorageWithoutContext) PutObject(key string, data []byte) error {
+	return f.storage.PutObjectWithContext(NewContext(context.Background()), key, data)
+}
+
+func (f *fileStorageWithoutContext) PutObjectFromReadSeeker(key string, reader io.ReadSeeker) error {
+	return f.storage.PutObjectFromReadSeekerWithContext(NewContext(context.Background()), key, reader)
+}
+
+func (f *fileStorageWithoutContext) PutPublicObject(key string, data []byte) error {
+	return f.storage.PutPublicObjectWithContext(NewContext(context.Background()), key, data)
+}
+
+func (f *fileStorageWithoutContext) PutPublicObjectFromReadSeeker(key string, reader io.ReadSeeker) error {
+	return f.storage.PutPublicObjectFromReadSeekerWithContext(NewContext(context.Background()), key, reader)
+}
+
+func (f *fileStorageWithoutContext) RemoveObject(key string) error {
+	return f.storage.RemoveObjectWithContext(NewContext(context.Background()), key)
+}
+
+func (f *fileStorageWithoutContext) GetJSONObject(key string, data interface{}) error {
+	return f.storage.GetJSONObjectWithContext(NewContext(context.Background()), key, data)
+}
+
+func (f *fileStorageWithoutContext) GetObjectURL(key string) (string, error) {
+	return f.storage.GetObjectURLWithContext(NewContext(context.Background()), key)
+}
+
+func (f *fileStorageWithoutContext) GetObjectPreSignURL(key string) (string, error) {
+	return f.storage.GetObjectPreSignURLWithContext(NewContext(context.Background()), key)
+}
+
+func (f *fileStorageWithoutContext) GetObjectReader(key string) (io.ReadCloser, error) {
+	return f.storage.GetObjectReaderWithContext(NewContext(context.Background()), key)
+}
+
+func (f *fileStorageWithoutContext) GetPreSignUploadURL(key string, size int64) (string, error) {
+	return f.storage.GetPreSignUploadURLWithContext(NewContext(context.Background()), key, size)
+}
+
+func (f *fileStorageWithoutContext) Exists(key string) (bool, error) {
+	return f.storage.ExistsWithContext(NewContext(context.Background()), key)
+}
+
+// contextReader fail reading once ctx is done
+type contextReader struct {
+	ctx context.Context
+	io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.Reader.Read(p)
+}
+
+type contextReadCloser struct {
+	contextReader
+	io.Closer
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package foundation
+
+import (
+	"bytes"
+	"context"
+	"github.com/octofoxio/foundation/logger"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// legacyFileStorage has only methods without context
+type legacyFileStorage struct {
+	FileStorage
+}
+
+// contextOnlyFileStorage has only methods with context
+type contextOnlyFileStorage struct {
+	FileStorageWithContext
+}
+
+func TestLocalFileStorage_WithContext(t *testing.T) {
+	var hook = &test.Hook{}
+	logger.AddHook(hook)
+
+	var local = NewLocalFileStorage(t.TempDir())
+	var _ StreamFileStorageWithContext = local
+	var _ StreamFileStorageWithContext = &S3FileStorage{}
+	assert.Equal(t, FileStorageWithContext(local), WithContextFileStorage(local))
+
+	ctx := context.WithValue(context.Background(), FoundationRequestIDContextKey, "req-1")
+	ctx = NewContext(ctx)
+	assert.NoError(t, local.PutObjectWithContext(ctx, "a.txt", []byte("hello")))
+	b, err := local.GetObjectWithContext(ctx, "a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), b)
+	assert.NoError(t, local.RemoveObjectWithContext(ctx, "a.txt"))
+	var logged bool
+	for _, entry := range hook.AllEntries() {
+		logged = logged || entry.Data["request-id"] == "req-1"
+	}
+	assert.True(t, logged, "storage log through logger of ctx")
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = local.GetObjectWithContext(canceled, "a.txt")
+	assert.Equal(t, context.Canceled, err)
+
+	// copying stop when ctx is done, partial file is removed
+	canceled, cancel = context.WithCancel(ctx)
+	reader := io.MultiReader(bytes.NewReader([]byte("part")), readerFunc(func(p []byte) (int, error) {
+		cancel()
+		return copy(p, "more"), nil
+	}), bytes.NewReader([]byte("rest")))
+	assert.Equal(t, context.Canceled, local.PutObjectFromReaderWithContext(canceled, "b.txt", reader))
+	exists, err := local.ExistsWithContext(ctx, "b.txt")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestWithContextFileStorage(t *testing.T) {
+	var local = NewLocalFileStorage(t.TempDir())
+	var storage = WithContextFileStorage(legacyFileStorage{local})
+	_, isAdapter := storage.(*fileStorageWithContext)
+	assert.True(t, isAdapter)
+
+	ctx := context.Background()
+	assert.NoError(t, storage.PutObjectWithContext(ctx, "a.json", []byte(`{"name": "john"}`)))
+	var data map[string]string
+	assert.NoError(t, storage.GetJSONObjectWithContext(ctx, "a.json", &data))
+	assert.Equal(t, "john", data["name"])
+
+	reader, err := storage.GetObjectReaderWithContext(ctx, "a.json")
+	assert.NoError(t, err)
+	b, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.NoError(t, reader.Close())
+	assert.Equal(t, `{"name": "john"}`, string(b))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Equal(t, context.Canceled, storage.RemoveObjectWithContext(canceled, "a.json"))
+	exists, err := local.Exists("a.json")
+	assert.NoError(t, err)
+	assert.True(t, exists, "call is not made after ctx is done")
+}
+
+func TestWithoutContextFileStorage(t *testing.T) {
+	var local = NewLocalFileStorage(t.TempDir())
+	assert.Equal(t, FileStorage(local), WithoutContextFileStorage(local))
+
+	var storage = WithoutContextFileStorage(contextOnlyFileStorage{local})
+	_, isAdapter := storage.(*fileStorageWithoutContext)
+	assert.True(t, isAdapter)
+	assert.NoError(t, storage.PutObject("a.txt", []byte("hello")))
+	exists, err := storage.Exists("a.txt")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.NoError(t, storage.RemoveObject("a.txt"))
+	exists, err = local.Exists("a.txt")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
@@ -0,0 +1,492 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package foundation
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/octofoxio/foundation/errors"
+	"io"
+	"io/ioutil"
+	url2 "net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStorageScheme is scheme of URLs which MemoryFileStorage return
+const MemoryStorageScheme = "mem"
+
+const (
+	memoryPreSignDuration       = 7 * 24 * time.Hour
+	memoryPreSignUploadDuration = 15 * time.Minute
+)
+
+type StorageOperation string
+
+const (
+	StorageOperationGet    StorageOperation = "get" // GetObject, GetJSONObject and GetObjectReader
+	StorageOperationPut    StorageOperation = "put"
+	StorageOperationRemove StorageOperation = "remove"
+	StorageOperationURL    StorageOperation = "url" // object, pre-signed and upload URLs
+	StorageOperationExists StorageOperation = "exists"
+)
+
+// StorageFault make matching calls of MemoryFileStorage slow and/or fail,
+// empty Operation match every operations and Key is path.Match pattern
+// (empty match every keys). Times limit number of calls which the fault
+// apply to, zero apply forever
+type StorageFault struct {
+	Operation StorageOperation
+	Key       string
+	Err       error
+	Latency   time.Duration
+	Times     int
+}
+
+func (f *StorageFault) match(operation StorageOperation, key string) bool {
+	if f.Operation != "" && f.Operation != operation {
+		return false
+	}
+	if f.Key == "" {
+		return true
+	}
+	matched, err := path.Match(f.Key, key)
+	return err == nil && matched
+}
+
+// StorageCall is call which MemoryFileStorage received
+type StorageCall struct {
+	Operation StorageOperation
+	Key       string
+}
+
+// MemoryObject is object stored in MemoryFileStorage
+type MemoryObject struct {
+	Key       string
+	Data      []byte
+	Public    bool
+	UpdatedAt time.Time
+}
+
+// MemoryFileStorage is FileStorage (with and without context) which keep
+// objects in memory for tests, it is safe for concurrent use. URLs use
+// MemoryStorageScheme (e.g. "mem://bucket/avatars/1.png") and can be
+// read with Fetch or written with Upload as client would do
+//
+// ```
+// var storage = foundation.NewMemoryFileStorage("test")
+// storage.InjectFault(foundation.StorageFault{Operation: foundation.StorageOperationPut, Key: "avatars/*", Err: errors.New(errors.ErrorTypeUnavailable, "slow down"), Times: 1})
+// err := service.UploadAvatar(ctx, storage, image)
+// assert.True(t, storage.Has("avatars/1.png"))
+// ```
+type MemoryFileStorage struct {
+	bucket  string
+	secret  []byte
+	mux     *sync.Mutex
+	objects map[string]*MemoryObject
+	faults  []*StorageFault
+	calls   []StorageCall
+	now     func() time.Time
+}
+
+func NewMemoryFileStorage(bucket string) *MemoryFileStorage {
+	var secret = make([]byte, 32)
+	_, _ = rand.Read(secret)
+	return &MemoryFileStorage{
+		bucket:  bucket,
+		secret:  secret,
+		mux:     &sync.Mutex{},
+		objects: map[string]*MemoryObject{},
+		now:     time.Now,
+	}
+}
+
+// InjectFault add fault, faults are matched in the order they are added
+func (m *MemoryFileStorage) InjectFault(fault StorageFault) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.faults = append(m.faults, &fault)
+}
+
+func (m *MemoryFileStorage) ClearFaults() {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.faults = nil
+}
+
+// Reset remove every objects, faults and recorded calls
+func (m *MemoryFileStorage) Reset() {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.objects = map[string]*MemoryObject{}
+	m.faults = nil
+	m.calls = nil
+}
+
+// Object return copy of stored object
+func (m *MemoryFileStorage) Object(key string) (MemoryObject, bool) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	o, ok := m.objects[key]
+	if !ok {
+		return MemoryObject{}, false
+	}
+	var result = *o
+	result.Data = append([]byte{}, o.Data...)
+	return result, true
+}
+
+func (m *MemoryFileStorage) Has(key string) bool {
+	_, ok := m.Object(key)
+	return ok
+}
+
+func (m *MemoryFileStorage) IsPublic(key string) bool {
+	o, ok := m.Object(key)
+	return ok && o.Public
+}
+
+// Keys return keys of stored objects in order
+func (m *MemoryFileStorage) Keys() []string {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	var keys = make([]string, 0, len(m.objects))
+	for key := range m.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Calls return calls which storage received in order, calls
+// which fail by injected fault are included
+func (m *MemoryFileStorage) Calls() []StorageCall {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return append([]StorageCall{}, m.calls...)
+}
+
+// CallCount return number of calls of operation,
+// empty key count calls of every keys
+func (m *MemoryFileStorage) CallCount(operation StorageOperation, key string) int {
+	var count = 0
+	for _, c := range m.Calls() {
+		if c.Operation == operation && (key == "" || c.Key == key) {
+			count++
+		}
+	}
+	return count
+}
+
+// call record the call and apply the first matching fault
+func (m *MemoryFileStorage) call(ctx context.Context, operation StorageOperation, key string) error {
+	m.mux.Lock()
+	m.calls = append(m.calls, StorageCall{Operation: operation, Key: key})
+	var fault *StorageFault
+	for i, f := range m.faults {
+		if f.match(operation, key) {
+			fault = f
+			if f.Times > 0 {
+				if f.Times--; f.Times == 0 {
+					m.faults = append(m.faults[:i:i], m.faults[i+1:]...)
+				}
+			}
+			break
+		}
+	}
+	m.mux.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if fault == nil {
+		return nil
+	}
+	if fault.Latency > 0 {
+		var timer = time.NewTimer(fault.Latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return fault.Err
+}
+
+func (m *MemoryFileStorage) put(key string, data []byte, public bool) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.objects[key] = &MemoryObject{
+		Key:       key,
+		Data:      append([]byte{}, data...),
+		Public:    public,
+		UpdatedAt: m.now(),
+	}
+}
+
+func (m *MemoryFileStorage) get(key string) ([]byte, error) {
+	o, ok := m.Object(key)
+	if !ok {
+		return nil, errors.New(errors.ErrorTypeNotfound, fmt.Sprintf("file %s not found", key))
+	}
+	return o.Data, nil
+}
+
+func (m *MemoryFileStorage) objectURL(key string) *url2.URL {
+	return &url2.URL{Scheme: MemoryStorageScheme, Host: m.bucket, Path: path.Join("/", key)}
+}
+
+// sign return signature of method, key, expiry and size of pre-signed URL
+func (m *MemoryFileStorage) sign(method string, key string, expires int64, size int64) string {
+	var mac = hmac.New(sha256.New, m.secret)
+	_, _ = fmt.Fprintf(mac, "%s\n%s\n%d\n%d", method, key, expires, size)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (m *MemoryFileStorage) preSignURL(method string, key string, duration time.Duration, size int64) string {
+	var u = m.objectURL(key)
+	var expires = m.now().Add(duration).Unix()
+	var query = url2.Values{}
+	query.Set("method", method)
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	if size >= 0 {
+		query.Set("size", strconv.FormatInt(size, 10))
+	}
+	query.Set("signature", m.sign(method, key, expires, size))
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// verify check URL of this storage and return key of object, query
+// is not allowed for object URL and it must be valid for pre-signed URL
+func (m *MemoryFileStorage) verify(rawURL string, method string) (key string, size int64, signed bool, err error) {
+	u, err := url2.Parse(rawURL)
+	if err != nil || u.Scheme != MemoryStorageScheme || u.Host != m.bucket {
+		return "", 0, false, errors.New(errors.ErrorTypeBadInput, fmt.Sprintf("%s is not URL of bucket %s", rawURL, m.bucket))
+	}
+	key = strings.TrimPrefix(u.Path, "/")
+	var query = u.Query()
+	if query.Get("signature") == "" {
+		return key, -1, false, nil
+	}
+	expires, _ := strconv.ParseInt(query.Get("expires"), 10, 64)
+	size = -1
+	if s := query.Get("size"); s != "" {
+		size, _ = strconv.ParseInt(s, 10, 64)
+	}
+	if query.Get("method") != method || !hmac.Equal([]byte(query.Get("signature")), []byte(m.sign(method, key, expires, size))) {
+		return "", 0, false, errors.New(errors.ErrorTypeForbidden, "signature does not match")
+	}
+	if m.now().Unix() > expires {
+		return "", 0, false, errors.New(errors.ErrorTypeForbidden, "URL is expired")
+	}
+	return key, size, true, nil
+}
+
+// Fetch read object by URL as client would do, private
+// object can be read only by valid pre-signed URL
+func (m *MemoryFileStorage) Fetch(rawURL string) ([]byte, error) {
+	key, _, signed, err := m.verify(rawURL, "GET")
+	if err != nil {
+		return nil, err
+	}
+	o, ok := m.Object(key)
+	if !ok {
+		return nil, errors.New(errors.ErrorTypeNotfound, fmt.Sprintf("file %s not found", key))
+	}
+	if !o.Public && !signed {
+		return nil, errors.New(errors.ErrorTypeForbidden, fmt.Sprintf("file %s is private", key))
+	}
+	return o.Data, nil
+}
+
+// Upload write data to pre-signed upload URL as client would do,
+// data must have the size which the URL is signed for
+func (m *MemoryFileStorage) Upload(rawURL string, data []byte) error {
+	key, size, signed, err := m.verify(rawURL, "PUT")
+	if err != nil {
+		return err
+	}
+	if !signed {
+		return errors.New(errors.ErrorTypeForbidden, "upload URL must be pre-signed")
+	}
+	if size >= 0 && int64(len(data)) != size {
+		return errors.New(errors.ErrorTypeBadInput, fmt.Sprintf("upload size %d does not match signed size %d", len(data), size))
+	}
+	m.put(key, data, false)
+	return nil
+}
+
+func (m *MemoryFileStorage) GetObject(key string) (result []byte, err error) {
+	return m.GetObjectWithContext(context.Background(), key)
+}
+
+func (m *MemoryFileStorage) GetObjectWithContext(ctx context.Context, key string) (result []byte, err error) {
+	if err := m.call(ctx, StorageOperationGet, key); err != nil {
+		return nil, err
+	}
+	return m.get(key)
+}
+
+func (m *MemoryFileStorage) PutObject(key string, data []byte) (err error) {
+	return m.PutObjectWithContext(context.Background(), key, data)
+}
+
+func (m *MemoryFileStorage) PutObjectWithContext(ctx context.Context, key string, data []byte) (err error) {
+	return m.PutObjectFromReaderWithContext(ctx, key, bytes.NewReader(data))
+}
+
+func (m *MemoryFileStorage) PutObjectFromReadSeeker(key string, reader io.ReadSeeker) (err error) {
+	return m.PutObjectFromReaderWithContext(context.Background(), key, reader)
+}
+
+func (m *MemoryFileStorage) PutObjectFromReadSeekerWithContext(ctx context.Context, key string, reader io.ReadSeeker) (err error) {
+	return m.PutObjectFromReaderWithContext(ctx, key, reader)
+}
+
+func (m *MemoryFileStorage) PutObjectFromReader(key string, reader io.Reader) (err error) {
+	return m.PutObjectFromReaderWithContext(context.Background(), key, reader)
+}
+
+func (m *MemoryFileStorage) PutObjectFromReaderWithContext(ctx context.Context, key string, reader io.Reader) (err error) {
+	return m.putFromReader(ctx, key, reader, false)
+}
+
+func (m *MemoryFileStorage) PutPublicObject(key string, data []byte) (err error) {
+	return m.PutPublicObjectWithContext(context.Background(), key, data)
+}
+
+func (m *MemoryFileStorage) PutPublicObjectWithContext(ctx context.Context, key string, data []byte) (err error) {
+	return m.PutPublicObjectFromReaderWithContext(ctx, key, bytes.NewReader(data))
+}
+
+func (m *MemoryFileStorage) PutPublicObjectFromReadSeeker(key string, reader io.ReadSeeker) (err error) {
+	return m.PutPublicObjectFromReaderWithContext(context.Background(), key, reader)
+}
+
+func (m *MemoryFileStorage) PutPublicObjectFromReadSeekerWithContext(ctx context.Context, key string, reader io.ReadSeeker) (err error) {
+	return m.PutPublicObjectFromReaderWithContext(ctx, key, reader)
+}
+
+func (m *MemoryFileStorage) PutPublicObjectFromReader(key string, reader io.Reader) (err error) {
+	return m.PutPublicObjectFromReaderWithContext(context.Background(), key, reader)
+}
+
+func (m *MemoryFileStorage) PutPublicObjectFromReaderWithContext(ctx context.Context, key string, reader io.Reader) (err error) {
+	return m.putFromReader(ctx, key, reader, true)
+}
+
+// putFromReader store object only when reader is read completely
+func (m *MemoryFileStorage) putFromReader(ctx context.Context, key string, reader io.Reader, public bool) error {
+	if err := m.call(ctx, StorageOperationPut, key); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(&contextReader{ctx: ctx, Reader: reader})
+	if err != nil {
+		return err
+	}
+	m.put(key, data, public)
+	return nil
+}
+
+func (m *MemoryFileStorage) RemoveObject(key string) (err error) {
+	return m.RemoveObjectWithContext(context.Background(), key)
+}
+
+// RemoveObjectWithContext does not fail when object does not exist, the same as S3
+func (m *MemoryFileStorage) RemoveObjectWithContext(ctx context.Context, key string) (err error) {
+	if err := m.call(ctx, StorageOperationRemove, key); err != nil {
+		return err
+	}
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *MemoryFileStorage) GetJSONObject(key string, data interface{}) (err error) {
+	return m.GetJSONObjectWithContext(context.Background(), key, data)
+}
+
+func (m *MemoryFileStorage) GetJSONObjectWithContext(ctx context.Context, key string, data interface{}) (err error) {
+	file, err := m.GetObjectWithContext(ctx, key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(file, data)
+}
+
+func (m *MemoryFileStorage) GetObjectURL(key string) (url string, err error) {
+	return m.GetObjectURLWithContext(context.Background(), key)
+}
+
+// GetObjectURLWithContext return URL of existing object, it can be
+// fetched only when the object is public
+func (m *MemoryFileStorage) GetObjectURLWithContext(ctx context.Context, key string) (url string, err error) {
+	if err := m.call(ctx, StorageOperationURL, key); err != nil {
+		return "", err
+	}
+	var objectURL = m.objectURL(key).String()
+	if !m.Has(key) {
+		return objectURL, errors.New(errors.ErrorTypeNotfound, fmt.Sprintf("file %s not found", key))
+	}
+	return objectURL, nil
+}
+
+func (m *MemoryFileStorage) GetObjectPreSignURL(key string) (url string, err error) {
+	return m.GetObjectPreSignURLWithContext(context.Background(), key)
+}
+
+func (m *MemoryFileStorage) GetObjectPreSignURLWithContext(ctx context.Context, key string) (url string, err error) {
+	if err := m.call(ctx, StorageOperationURL, key); err != nil {
+		return "", err
+	}
+	return m.preSignURL("GET", key, memoryPreSignDuration, -1), nil
+}
+
+func (m *MemoryFileStorage) GetObjectReader(key string) (result io.ReadCloser, err error) {
+	return m.GetObjectReaderWithContext(context.Background(), key)
+}
+
+func (m *MemoryFileStorage) GetObjectReaderWithContext(ctx context.Context, key string) (result io.ReadCloser, err error) {
+	data, err := m.GetObjectWithContext(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *MemoryFileStorage) GetPreSignUploadURL(key string, size int64) (url string, err error) {
+	return m.GetPreSignUploadURLWithContext(context.Background(), key, size)
+}
+
+func (m *MemoryFileStorage) GetPreSignUploadURLWithContext(ctx context.Context, key string, size int64) (url string, err error) {
+	if err := m.call(ctx, StorageOperationURL, key); err != nil {
+		return "", err
+	}
+	return m.preSignURL("PUT", key, memoryPreSignUploadDuration, size), nil
+}
+
+func (m *MemoryFileStorage) Exists(key string) (exists bool, err error) {
+	return m.ExistsWithContext(context.Background(), key)
+}
+
+func (m *MemoryFileStorage) ExistsWithContext(ctx context.Context, key string) (exists bool, err error) {
+	if err := m.call(ctx, StorageOperationExists, key); err != nil {
+		return false, err
+	}
+	return m.Has(key), nil
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) 2019. Octofox.io
+ */
+
+package foundation
+
+import (
+	"context"
+	"fmt"
+	"github.com/octofoxio/foundation/errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryFileStorage(t *testing.T) {
+	var storage = NewMemoryFileStorage("test")
+	var _ FileStorage = storage
+	var _ StreamFileStorage = storage
+	var _ StreamFileStorageWithContext = storage
+
+	assert.NoError(t, storage.PutObject("private.txt", []byte("secret")))
+	assert.NoError(t, storage.PutPublicObjectFromReader("public.txt", strings.NewReader("hello")))
+	assert.NoError(t, storage.PutObject("data.json", []byte(`{"name":"fox"}`)))
+	assert.Equal(t, []string{"data.json", "private.txt", "public.txt"}, storage.Keys())
+	assert.True(t, storage.IsPublic("public.txt"))
+	assert.False(t, storage.IsPublic("private.txt"))
+
+	b, err := storage.GetObject("private.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("secret"), b)
+	b[0] = 'S'
+	o, _ := storage.Object("private.txt")
+	assert.Equal(t, []byte("secret"), o.Data, "returned data is copied")
+
+	reader, err := storage.GetObjectReader("public.txt")
+	assert.NoError(t, err)
+	b, _ = ioutil.ReadAll(reader)
+	assert.Equal(t, []byte("hello"), b)
+
+	var data struct{ Name string }
+	assert.NoError(t, storage.GetJSONObject("data.json", &data))
+	assert.Equal(t, "fox", data.Name)
+
+	_, err = storage.GetObject("missing.txt")
+	assert.Equal(t, errors.ErrorTypeNotfound, errors.From(err).Type())
+	exists, err := storage.Exists("missing.txt")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.NoError(t, storage.RemoveObject("data.json"))
+	assert.NoError(t, storage.RemoveObject("data.json"))
+	assert.False(t, storage.Has("data.json"))
+	assert.Equal(t, 2, storage.CallCount(StorageOperationRemove, "data.json"))
+
+	storage.Reset()
+	assert.Empty(t, storage.Keys())
+	assert.Empty(t, storage.Calls())
+}
+
+func TestMemoryFileStorage_URL(t *testing.T) {
+	var storage = NewMemoryFileStorage("test")
+	assert.NoError(t, storage.PutObject("private.txt", []byte("secret")))
+	assert.NoError(t, storage.PutPublicObject("public.txt", []byte("hello")))
+
+	u, err := storage.GetObjectURL("public.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "mem://test/public.txt", u)
+	b, err := storage.Fetch(u)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), b)
+
+	u, err = storage.GetObjectURL("private.txt")
+	assert.NoError(t, err)
+	_, err = storage.Fetch(u)
+	assert.Equal(t, errors.ErrorTypeForbidden, errors.From(err).Type())
+
+	_, err = storage.GetObjectURL("missing.txt")
+	assert.Equal(t, errors.ErrorTypeNotfound, errors.From(err).Type())
+
+	u, err = storage.GetObjectPreSignURL("private.txt")
+	assert.NoError(t, err)
+	b, err = storage.Fetch(u)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("secret"), b)
+	_, err = storage.Fetch(strings.Replace(u, "private.txt", "public.txt", 1))
+	assert.Equal(t, errors.ErrorTypeForbidden, errors.From(err).Type(), "signature is bound to key")
+	_, err = NewMemoryFileStorage("test").Fetch(u)
+	assert.Equal(t, errors.ErrorTypeForbidden, errors.From(err).Type(), "signature is bound to storage")
+	_, err = storage.Fetch("mem://other/private.txt")
+	assert.Equal(t, errors.ErrorTypeBadInput, errors.From(err).Type())
+
+	u, err = storage.GetPreSignUploadURL("upload.txt", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, errors.ErrorTypeBadInput, errors.From(storage.Upload(u, []byte("too long"))).Type())
+	assert.NoError(t, storage.Upload(u, []byte("12345")))
+	assert.True(t, storage.Has("upload.txt"))
+	assert.False(t, storage.IsPublic("upload.txt"))
+	_, err = storage.Fetch(u)
+	assert.Equal(t, errors.ErrorTypeForbidden, errors.From(err).Type(), "upload URL cannot be used to read")
+
+	var now = time.Now()
+	storage.now = func() time.Time { return now.Add(time.Hour) }
+	assert.Equal(t, errors.ErrorTypeForbidden, errors.From(storage.Upload(u, []byte("12345"))).Type(), "URL is expired")
+}
+
+func TestMemoryFileStorage_Fault(t *testing.T) {
+	var storage = NewMemoryFileStorage("test")
+	var unavailable = errors.New(errors.ErrorTypeUnavailable, "slow down")
+	storage.InjectFault(StorageFault{Operation: StorageOperationPut, Key: "avatars/*", Err: unavailable, Times: 1})
+
+	assert.Equal(t, unavailable, storage.PutObject("avatars/1.png", []byte("1")))
+	assert.False(t, storage.Has("avatars/1.png"))
+	assert.NoError(t, storage.PutObject("avatars/1.png", []byte("1")), "fault apply only once")
+	assert.Equal(t, 2, storage.CallCount(StorageOperationPut, "avatars/1.png"))
+
+	storage.InjectFault(StorageFault{Key: "broken.txt", Err: unavailable})
+	assert.Equal(t, unavailable, storage.PutObject("broken.txt", []byte("1")))
+	_, err := storage.Exists("broken.txt")
+	assert.Equal(t, unavailable, err)
+	_, err = storage.GetObject("avatars/1.png")
+	assert.NoError(t, err)
+	storage.ClearFaults()
+	assert.NoError(t, storage.PutObject("broken.txt", []byte("1")))
+
+	// latency is canceled with ctx
+	storage.InjectFault(StorageFault{Operation: StorageOperationGet, Latency: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = storage.GetObjectWithContext(ctx, "broken.txt")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	storage.ClearFaults()
+	storage.InjectFault(StorageFault{Operation: StorageOperationGet, Latency: 20 * time.Millisecond})
+	var start = time.Now()
+	_, err = storage.GetObject("broken.txt")
+	assert.NoError(t, err)
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+}
+
+func TestMemoryFileStorage_Concurrent(t *testing.T) {
+	var storage = NewMemoryFileStorage("test")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("%d.txt", i)
+			assert.NoError(t, storage.PutObject(key, []byte(key)))
+			_, err := storage.GetObject(key)
+			assert.NoError(t, err)
+			_ = storage.Keys()
+		}(i)
+	}
+	wg.Wait()
+	assert.Len(t, storage.Keys(), 20)
+	assert.Equal(t, 20, storage.CallCount(StorageOperationGet, ""))
+}
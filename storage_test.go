@@ -11,9 +11,11 @@ import (
 	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/octofoxio/foundation/errors"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"io/ioutil"
 	"os"
 	"testing"
+	"testing/iotest"
 )
 
 func TestNewLocalFileStorage(t *testing.T) {
@@ -143,3 +145,27 @@ func TestFileExists(t *testing.T) {
 	assert.Nil(t, err)
 	assert.False(t, exists)
 }
+
+func TestLocalFileStorage_PutObjectFromReader(t *testing.T) {
+	local := NewLocalFileStorage(t.TempDir())
+	var _ StreamFileStorage = local
+	var _ StreamFileStorage = &S3FileStorage{}
+
+	err := local.PutObjectFromReader("uploads/a.txt", bytes.NewReader([]byte("streamed")))
+	assert.NoError(t, err)
+	b, err := local.GetObject("uploads/a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("streamed"), b)
+
+	// partial file is removed
+	err = local.PutObjectFromReader("uploads/b.txt", io.MultiReader(bytes.NewReader([]byte("part")), iotest.ErrReader(errors.New(errors.ErrorTypeBadInput, "broken"))))
+	assert.Error(t, err)
+	exists, err := local.Exists("uploads/b.txt")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestLocalFileStorage_GetPreSignUploadURL(t *testing.T) {
+	_, err := NewLocalFileStorage(t.TempDir()).GetPreSignUploadURL("a.txt", 10)
+	assert.Equal(t, errors.ErrorTypeNotImplemented, errors.From(err).Type())
+}